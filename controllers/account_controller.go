@@ -2,8 +2,12 @@ package controllers
 
 import (
 	"database/sql"
+	"errors"
+	"io"
 	"net/http"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
@@ -14,7 +18,13 @@ import (
 type accountPayload struct {
 	Label        string `json:"label" binding:"required"`
 	NetflixEmail string `json:"netflix_email" binding:"required,email"`
-	Status       string `json:"status" binding:"required,oneof=active inactive"`
+	Status       string `json:"status" binding:"required"`
+	TemplateID   *int64 `json:"template_id"`
+}
+
+type statusPayload struct {
+	Status string `json:"status" binding:"required"`
+	Note   string `json:"note"`
 }
 
 func GetAccounts(c *gin.Context) {
@@ -23,13 +33,50 @@ func GetAccounts(c *gin.Context) {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
+
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		limit = n
+	}
+
+	staleDays := 0
+	if raw := c.Query("stale_days"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid stale_days"})
+			return
+		}
+		staleDays = n
+	}
+
+	query := services.AccountQuery{
+		Search:    c.Query("q"),
+		Status:    c.Query("status"),
+		Tags:      c.QueryArray("tag"),
+		Folder:    c.Query("folder"),
+		StaleDays: staleDays,
+		Sort:      c.Query("sort"),
+		Order:     c.Query("order"),
+		Cursor:    c.Query("cursor"),
+		Limit:     limit,
+	}
+
 	ctx := c.Request.Context()
-	accounts, err := services.ListAccounts(ctx, database.GetDB(), userID)
+	page, err := services.ListAccounts(ctx, database.GetDB(), userID, query)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, accounts)
+	c.JSON(http.StatusOK, page)
 }
 
 func CreateAccount(c *gin.Context) {
@@ -45,11 +92,12 @@ func CreateAccount(c *gin.Context) {
 		return
 	}
 
-	account, err := services.CreateAccount(c.Request.Context(), database.GetDB(), userID, payload.Label, payload.NetflixEmail, payload.Status)
+	account, err := services.CreateAccount(c.Request.Context(), database.GetDB(), userID, payload.Label, payload.NetflixEmail, payload.Status, payload.TemplateID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondAccountError(c, err)
 		return
 	}
+	setETag(c, account.Version)
 	c.JSON(http.StatusCreated, account)
 }
 
@@ -66,7 +114,7 @@ func GetAccountByID(c *gin.Context) {
 		return
 	}
 
-	account, err := services.GetAccount(c.Request.Context(), database.GetDB(), id, userID)
+	account, err := services.GetAccountDetail(c.Request.Context(), database.GetDB(), id, userID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			c.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
@@ -75,6 +123,7 @@ func GetAccountByID(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	setETag(c, account.Version)
 
 	c.JSON(http.StatusOK, account)
 }
@@ -98,20 +147,17 @@ func UpdateAccount(c *gin.Context) {
 		return
 	}
 
-	account, err := services.UpdateAccount(c.Request.Context(), database.GetDB(), id, userID, payload.Label, payload.NetflixEmail, payload.Status)
+	account, err := services.UpdateAccount(c.Request.Context(), database.GetDB(), id, userID, payload.Label, payload.NetflixEmail, payload.Status, ifMatchVersion(c))
 	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondAccountError(c, err)
 		return
 	}
+	setETag(c, account.Version)
 
 	c.JSON(http.StatusOK, account)
 }
 
-func DeleteAccount(c *gin.Context) {
+func ChangeAccountStatus(c *gin.Context) {
 	userID, ok := currentUserID(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -124,19 +170,23 @@ func DeleteAccount(c *gin.Context) {
 		return
 	}
 
-	if err := services.DeleteAccount(c.Request.Context(), database.GetDB(), id, userID); err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	var payload statusPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.Status(http.StatusNoContent)
+	account, err := services.ChangeAccountStatus(c.Request.Context(), database.GetDB(), id, userID, payload.Status, payload.Note, ifMatchVersion(c))
+	if err != nil {
+		respondAccountError(c, err)
+		return
+	}
+	setETag(c, account.Version)
+
+	c.JSON(http.StatusOK, account)
 }
 
-func OpenAccountSession(c *gin.Context) {
+func GetAccountStatusHistory(c *gin.Context) {
 	userID, ok := currentUserID(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -151,6 +201,37 @@ func OpenAccountSession(c *gin.Context) {
 
 	account, err := services.GetAccount(c.Request.Context(), database.GetDB(), id, userID)
 	if err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	history, err := services.GetStatusHistory(c.Request.Context(), database.GetDB(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":              account.Status,
+		"allowed_transitions": services.AllowedStatusTransitions(account.Status),
+		"history":             history,
+	})
+}
+
+func DeleteAccount(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account id"})
+		return
+	}
+
+	if err := services.DeleteAccount(c.Request.Context(), database.GetDB(), id, userID); err != nil {
 		if err == sql.ErrNoRows {
 			c.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
 			return
@@ -159,20 +240,248 @@ func OpenAccountSession(c *gin.Context) {
 		return
 	}
 
-	tabs, err := services.GetTabs(c.Request.Context(), database.GetDB(), id)
+	c.Status(http.StatusNoContent)
+}
+
+const maxImportBytes = 5 << 20
+
+// ImportAccounts creates accounts from an uploaded CSV or JSON file. With
+// ?dry_run=true it only reports per-row validation errors and duplicates.
+func ImportAccounts(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+
+	body := io.Reader(c.Request.Body)
+	format := strings.ToLower(c.Query("format"))
+	if c.ContentType() == "multipart/form-data" {
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cannot read uploaded file"})
+			return
+		}
+		defer f.Close()
+		body = f
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(file.Filename)), ".")
+		}
+	}
+	if format == "" {
+		if strings.Contains(c.ContentType(), "csv") {
+			format = "csv"
+		} else {
+			format = "json"
+		}
+	}
+
+	var rows []services.ImportRow
+	var err error
+	switch format {
+	case "csv":
+		rows, err = services.ParseImportCSV(body)
+	case "json":
+		rows, err = services.ParseImportJSON(body)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or json"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := services.ImportAccounts(c.Request.Context(), database.GetDB(), userID, rows, dryRun)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := services.LaunchChrome(account, tabs); err != nil {
+	switch {
+	case dryRun:
+		c.JSON(http.StatusOK, result)
+	case result.Valid != result.Total:
+		c.JSON(http.StatusUnprocessableEntity, result)
+	default:
+		c.JSON(http.StatusCreated, result)
+	}
+}
+
+// GetTrashedAccounts lists the caller's deleted accounts that can still be restored.
+func GetTrashedAccounts(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	accounts, err := services.ListTrashedAccounts(c.Request.Context(), database.GetDB(), userID)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"accounts": accounts, "retention_days": services.LoadTrashConfig().RetentionDays})
+}
+
+func RestoreAccount(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account id"})
+		return
+	}
+
+	account, err := services.RestoreAccount(c.Request.Context(), database.GetDB(), id, userID)
+	if err != nil {
+		respondAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, account)
+}
+
+// OpenAccountSession launches Chrome for the account. ?tabs=1,2 opens only
+// those tabs instead of the ones picked by the tabs' launch flags.
+func OpenAccountSession(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account id"})
+		return
+	}
+
+	var tabIDs []int64
+	if raw := strings.TrimSpace(c.Query("tabs")); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			tabID, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tab id in tabs"})
+				return
+			}
+			tabIDs = append(tabIDs, tabID)
+		}
+	}
+
+	warnings, err := services.OpenAccount(c.Request.Context(), database.GetDB(), id, userID, tabIDs)
+	if err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	response := gin.H{"status": "launching"}
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+	c.JSON(http.StatusOK, response)
+}
 
-	c.JSON(http.StatusOK, gin.H{"status": "launching"})
+// respondAccountError maps account service errors to HTTP responses.
+func respondAccountError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
+	case errors.Is(err, services.ErrInvalidStatus), errors.Is(err, services.ErrInvalidTemplate), errors.Is(err, services.ErrInvalidTab):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrIllegalStatusTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrVersionMismatch):
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSessionLimit):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
 }
 
 func parseID(idParam string) (int64, error) {
 	return strconv.ParseInt(idParam, 10, 64)
 }
+
+type bulkFilterPayload struct {
+	Search    string   `json:"q"`
+	Status    string   `json:"status"`
+	Tags      []string `json:"tags"`
+	Folder    string   `json:"folder"`
+	StaleDays int      `json:"stale_days"`
+}
+
+type bulkPayload struct {
+	Action string             `json:"action" binding:"required"`
+	IDs    []int64            `json:"ids"`
+	Filter *bulkFilterPayload `json:"filter"`
+	Status string             `json:"status"`
+	Note   string             `json:"note"`
+	TagID  int64              `json:"tag_id"`
+	Atomic bool               `json:"atomic"`
+}
+
+// BulkAccounts applies one action (set-status, add-tag, remove-tag, delete
+// or open) to a list of accounts or to every account matching a filter.
+func BulkAccounts(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var payload bulkPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	action := services.BulkAction{
+		Action: payload.Action,
+		IDs:    payload.IDs,
+		Status: payload.Status,
+		Note:   payload.Note,
+		TagID:  payload.TagID,
+		Atomic: payload.Atomic,
+	}
+	if payload.Filter != nil {
+		action.Filter = &services.AccountQuery{
+			Search:    payload.Filter.Search,
+			Status:    payload.Filter.Status,
+			Tags:      payload.Filter.Tags,
+			Folder:    payload.Filter.Folder,
+			StaleDays: payload.Filter.StaleDays,
+		}
+	}
+
+	result, err := services.RunBulkAction(c.Request.Context(), database.GetDB(), userID, action)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+		case errors.Is(err, services.ErrInvalidBulk), errors.Is(err, services.ErrInvalidQuery), errors.Is(err, services.ErrInvalidStatus):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	if result.RolledBack {
+		c.JSON(http.StatusConflict, result)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"netflix_central/database"
+	"netflix_central/services"
+)
+
+type accountVariablesPayload struct {
+	Variables map[string]string `json:"variables" binding:"required"`
+}
+
+// GetVariablesByAccount returns the variables the account's tab URLs can use.
+func GetVariablesByAccount(c *gin.Context) {
+	_, accountID, ok := ownedAccountParam(c)
+	if !ok {
+		return
+	}
+
+	vars, err := services.GetAccountVariables(c.Request.Context(), database.GetDB(), accountID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"variables": vars})
+}
+
+// SetVariablesForAccount replaces the account's variables.
+func SetVariablesForAccount(c *gin.Context) {
+	userID, accountID, ok := ownedAccountParam(c)
+	if !ok {
+		return
+	}
+
+	var payload accountVariablesPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	vars, err := services.SetAccountVariables(c.Request.Context(), database.GetDB(), accountID, userID, payload.Variables)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
+		case errors.Is(err, services.ErrInvalidVariable):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"variables": vars})
+}
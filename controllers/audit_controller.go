@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"netflix_central/database"
+	"netflix_central/models"
+	"netflix_central/services"
+)
+
+// GetAuditEvents lists the caller's audit events, newest first. Pass the
+// returned next_before_id as before_id to load the next page.
+func GetAuditEvents(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	events, nextBeforeID, err := services.ListAuditEvents(c.Request.Context(), database.GetDB(), userID, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var next *int64
+	if nextBeforeID != 0 {
+		next = &nextBeforeID
+	}
+	c.JSON(http.StatusOK, gin.H{"events": events, "next_before_id": next})
+}
+
+// ExportAuditEvents streams every matching event as JSON Lines.
+func ExportAuditEvents(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", `attachment; filename="audit.jsonl"`)
+	c.Status(http.StatusOK)
+
+	enc := json.NewEncoder(c.Writer)
+	err = services.ExportAuditEvents(c.Request.Context(), database.GetDB(), userID, filter, func(e models.AuditEvent) error {
+		return enc.Encode(e)
+	})
+	if err != nil {
+		// Headers are already sent; the truncated stream is all we can signal.
+		c.Error(err)
+	}
+}
+
+func parseAuditFilter(c *gin.Context) (services.AuditFilter, error) {
+	filter := services.AuditFilter{
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+	}
+
+	var err error
+	if filter.TargetID, err = parseOptionalID(c.Query("target_id")); err != nil {
+		return filter, errors.New("invalid target_id")
+	}
+	if filter.BeforeID, err = parseOptionalID(c.Query("before_id")); err != nil {
+		return filter, errors.New("invalid before_id")
+	}
+	if filter.Since, err = parseAuditTime(c.Query("since"), false); err != nil {
+		return filter, errors.New("since must be an RFC3339 time or YYYY-MM-DD date")
+	}
+	if filter.Until, err = parseAuditTime(c.Query("until"), true); err != nil {
+		return filter, errors.New("until must be an RFC3339 time or YYYY-MM-DD date")
+	}
+	if raw := c.Query("limit"); raw != "" {
+		if filter.Limit, err = strconv.Atoi(raw); err != nil || filter.Limit <= 0 {
+			return filter, errors.New("invalid limit")
+		}
+	}
+	return filter, nil
+}
+
+func parseOptionalID(raw string) (int64, error) {
+	if strings.TrimSpace(raw) == "" {
+		return 0, nil
+	}
+	return parseID(raw)
+}
+
+// parseAuditTime accepts a timestamp or a plain date. A date used as the upper
+// bound includes the whole day.
+func parseAuditTime(raw string, endOfDay bool) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
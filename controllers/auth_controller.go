@@ -1,6 +1,8 @@
 package controllers
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"os"
 	"strings"
@@ -39,8 +41,12 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	user, err := services.CreateUser(payload.Email, payload.Password)
+	user, err := services.CreateUser(requestContext(c), payload.Email, payload.Password)
 	if err != nil {
+		if errors.Is(err, services.ErrWeakPassword) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create user"})
 		return
 	}
@@ -60,7 +66,7 @@ func Login(c *gin.Context) {
 		return
 	}
 	payload.Email = strings.TrimSpace(strings.ToLower(payload.Email))
-	user, err := services.ValidateUser(payload.Email, payload.Password)
+	user, err := services.ValidateUser(requestContext(c), payload.Email, payload.Password)
 	if err != nil {
 		switch err {
 		case services.ErrUserNotFound:
@@ -96,3 +102,12 @@ func generateToken(userID int64, email string) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(secret))
 }
+
+// requestContext returns the request context carrying the client address, for
+// unauthenticated endpoints that still write audit events.
+func requestContext(c *gin.Context) context.Context {
+	return services.WithActor(c.Request.Context(), services.Actor{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+}
@@ -1,6 +1,11 @@
 package controllers
 
-import "github.com/gin-gonic/gin"
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
 
 // currentUserID retrieves the authenticated user id from context.
 func currentUserID(c *gin.Context) (int64, bool) {
@@ -11,3 +16,24 @@ func currentUserID(c *gin.Context) (int64, bool) {
 	id, ok := val.(int64)
 	return id, ok
 }
+
+// setETag sends a resource version as its ETag.
+func setETag(c *gin.Context, version int) {
+	c.Header("ETag", `"`+strconv.Itoa(version)+`"`)
+}
+
+// ifMatchVersion reads the version from an If-Match header. It returns 0
+// when the header is missing or "*", and -1, which never matches, when it
+// cannot be parsed.
+func ifMatchVersion(c *gin.Context) int {
+	raw := strings.TrimSpace(c.GetHeader("If-Match"))
+	if raw == "" || raw == "*" {
+		return 0
+	}
+	raw = strings.Trim(strings.TrimPrefix(raw, "W/"), `"`)
+	version, err := strconv.Atoi(raw)
+	if err != nil || version <= 0 {
+		return -1
+	}
+	return version
+}
@@ -0,0 +1,339 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"netflix_central/database"
+	"netflix_central/services"
+)
+
+type customerPayload struct {
+	Name    string `json:"name" binding:"required"`
+	Contact string `json:"contact"`
+	Notes   string `json:"notes"`
+}
+
+type seatPayload struct {
+	CustomerID int64  `json:"customer_id" binding:"required"`
+	AccountID  int64  `json:"account_id" binding:"required"`
+	ProfileID  *int64 `json:"profile_id"`
+	StartsOn   string `json:"starts_on" binding:"required"`
+	EndsOn     string `json:"ends_on"`
+	Notes      string `json:"notes"`
+}
+
+func (p seatPayload) input() services.SeatInput {
+	return services.SeatInput{
+		CustomerID: p.CustomerID,
+		AccountID:  p.AccountID,
+		ProfileID:  p.ProfileID,
+		StartsOn:   p.StartsOn,
+		EndsOn:     p.EndsOn,
+		Notes:      p.Notes,
+	}
+}
+
+func GetCustomers(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	customers, err := services.ListCustomers(c.Request.Context(), database.GetDB(), userID, c.Query("q"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, customers)
+}
+
+func GetCustomerByID(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid customer id"})
+		return
+	}
+
+	customer, err := services.GetCustomer(c.Request.Context(), database.GetDB(), id, userID)
+	if err != nil {
+		respondCustomerError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, customer)
+}
+
+func CreateCustomer(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var payload customerPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customer, err := services.CreateCustomer(c.Request.Context(), database.GetDB(), userID, services.CustomerInput{
+		Name:    payload.Name,
+		Contact: payload.Contact,
+		Notes:   payload.Notes,
+	})
+	if err != nil {
+		respondCustomerError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, customer)
+}
+
+func UpdateCustomer(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid customer id"})
+		return
+	}
+
+	var payload customerPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customer, err := services.UpdateCustomer(c.Request.Context(), database.GetDB(), id, userID, services.CustomerInput{
+		Name:    payload.Name,
+		Contact: payload.Contact,
+		Notes:   payload.Notes,
+	})
+	if err != nil {
+		respondCustomerError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, customer)
+}
+
+func DeleteCustomer(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid customer id"})
+		return
+	}
+
+	if err := services.DeleteCustomer(c.Request.Context(), database.GetDB(), id, userID); err != nil {
+		respondCustomerError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func GetSeatsByCustomer(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid customer id"})
+		return
+	}
+
+	if _, err := services.GetCustomer(c.Request.Context(), database.GetDB(), id, userID); err != nil {
+		respondCustomerError(c, err)
+		return
+	}
+
+	seats, err := services.ListCustomerSeats(c.Request.Context(), database.GetDB(), id, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, seats)
+}
+
+func GetOccupantsByAccount(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	accountID, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account id"})
+		return
+	}
+
+	on, ok := parseDateQuery(c)
+	if !ok {
+		return
+	}
+
+	if _, err := services.GetAccount(c.Request.Context(), database.GetDB(), accountID, userID); err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	seats, err := services.ListAccountOccupants(c.Request.Context(), database.GetDB(), accountID, userID, on)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, seats)
+}
+
+func CreateSeat(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var payload seatPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	seat, err := services.CreateSeat(c.Request.Context(), database.GetDB(), userID, payload.input())
+	if err != nil {
+		respondSeatError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, seat)
+}
+
+func UpdateSeat(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid seat id"})
+		return
+	}
+
+	var payload seatPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	seat, err := services.UpdateSeat(c.Request.Context(), database.GetDB(), id, userID, payload.input())
+	if err != nil {
+		respondSeatError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, seat)
+}
+
+func DeleteSeat(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid seat id"})
+		return
+	}
+
+	if err := services.DeleteSeat(c.Request.Context(), database.GetDB(), id, userID); err != nil {
+		respondSeatError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func GetFreeSeats(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	on, ok := parseDateQuery(c)
+	if !ok {
+		return
+	}
+
+	report, err := services.FreeSeatReport(c.Request.Context(), database.GetDB(), userID, on)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	total := 0
+	for _, r := range report {
+		total += r.Free
+	}
+	c.JSON(http.StatusOK, gin.H{"date": on.Format("2006-01-02"), "total_free": total, "accounts": report})
+}
+
+// parseDateQuery reads the optional ?date=YYYY-MM-DD parameter, defaulting to today.
+func parseDateQuery(c *gin.Context) (time.Time, bool) {
+	raw := c.Query("date")
+	if raw == "" {
+		return time.Now().UTC(), true
+	}
+	on, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"})
+		return time.Time{}, false
+	}
+	return on, true
+}
+
+func respondCustomerError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "customer not found"})
+	case errors.Is(err, services.ErrInvalidCustomer):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func respondSeatError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "seat not found"})
+	case errors.Is(err, services.ErrInvalidSeat):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNoFreeSeat):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"netflix_central/database"
+	"netflix_central/services"
+)
+
+type folderPayload struct {
+	Name     string `json:"name" binding:"required"`
+	ParentID *int64 `json:"parent_id"`
+}
+
+type accountFolderPayload struct {
+	FolderID *int64 `json:"folder_id"`
+}
+
+func GetFolders(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	folders, err := services.ListFolders(c.Request.Context(), database.GetDB(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, folders)
+}
+
+func CreateFolder(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var payload folderPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	folder, err := services.CreateFolder(c.Request.Context(), database.GetDB(), userID, payload.Name, payload.ParentID)
+	if err != nil {
+		respondFolderError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, folder)
+}
+
+func UpdateFolder(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid folder id"})
+		return
+	}
+
+	var payload folderPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	folder, err := services.UpdateFolder(c.Request.Context(), database.GetDB(), id, userID, payload.Name, payload.ParentID)
+	if err != nil {
+		respondFolderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, folder)
+}
+
+func DeleteFolder(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid folder id"})
+		return
+	}
+
+	if err := services.DeleteFolder(c.Request.Context(), database.GetDB(), id, userID); err != nil {
+		respondFolderError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func SetFolderForAccount(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	accountID, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account id"})
+		return
+	}
+
+	var payload accountFolderPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := services.SetAccountFolder(c.Request.Context(), database.GetDB(), accountID, userID, payload.FolderID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
+			return
+		}
+		respondFolderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, account)
+}
+
+func respondFolderError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "folder not found"})
+	case errors.Is(err, services.ErrInvalidFolder):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
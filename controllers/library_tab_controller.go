@@ -0,0 +1,187 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"netflix_central/database"
+	"netflix_central/services"
+)
+
+type libraryTabPayload struct {
+	Title string `json:"title"`
+	URL   string `json:"url" binding:"required"`
+}
+
+type attachLibraryTabPayload struct {
+	AccountIDs []int64 `json:"account_ids" binding:"required"`
+}
+
+func GetLibraryTabs(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	tabs, err := services.ListLibraryTabs(c.Request.Context(), database.GetDB(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tabs)
+}
+
+func GetLibraryTabByID(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid library tab id"})
+		return
+	}
+
+	tab, err := services.GetLibraryTab(c.Request.Context(), database.GetDB(), id, userID)
+	if err != nil {
+		respondLibraryTabError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tab)
+}
+
+func CreateLibraryTab(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var payload libraryTabPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tab, err := services.CreateLibraryTab(c.Request.Context(), database.GetDB(), userID, payload.Title, payload.URL)
+	if err != nil {
+		respondLibraryTabError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, tab)
+}
+
+// UpdateLibraryTab changes the library entry for every account linking it.
+func UpdateLibraryTab(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid library tab id"})
+		return
+	}
+
+	var payload libraryTabPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tab, err := services.UpdateLibraryTab(c.Request.Context(), database.GetDB(), id, userID, payload.Title, payload.URL)
+	if err != nil {
+		respondLibraryTabError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tab)
+}
+
+func DeleteLibraryTab(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid library tab id"})
+		return
+	}
+
+	if err := services.DeleteLibraryTab(c.Request.Context(), database.GetDB(), id, userID); err != nil {
+		respondLibraryTabError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetLibraryTabAccounts lists the accounts that use a library tab.
+func GetLibraryTabAccounts(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid library tab id"})
+		return
+	}
+
+	usages, err := services.LibraryTabUsages(c.Request.Context(), database.GetDB(), id, userID)
+	if err != nil {
+		respondLibraryTabError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, usages)
+}
+
+// AttachLibraryTab links a library tab to the given accounts.
+func AttachLibraryTab(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid library tab id"})
+		return
+	}
+
+	var payload attachLibraryTabPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := services.AttachLibraryTab(c.Request.Context(), database.GetDB(), id, userID, payload.AccountIDs)
+	if err != nil {
+		respondLibraryTabError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"accounts": results})
+}
+
+func respondLibraryTabError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "library tab not found"})
+	case errors.Is(err, services.ErrInvalidLibraryTab), errors.Is(err, services.ErrInvalidTab):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"netflix_central/database"
+	"netflix_central/services"
+)
+
+type profilePayload struct {
+	Name           string `json:"name" binding:"required"`
+	AssignedClient string `json:"assigned_client"`
+	PinProtected   bool   `json:"pin_protected"`
+	MaturityLevel  string `json:"maturity_level"`
+	Notes          string `json:"notes"`
+}
+
+func (p profilePayload) input() services.ProfileInput {
+	return services.ProfileInput{
+		Name:           p.Name,
+		AssignedClient: p.AssignedClient,
+		PinProtected:   p.PinProtected,
+		MaturityLevel:  p.MaturityLevel,
+		Notes:          p.Notes,
+	}
+}
+
+func GetProfilesByAccount(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	accountID, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account id"})
+		return
+	}
+
+	if _, err := services.GetAccount(c.Request.Context(), database.GetDB(), accountID, userID); err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	profiles, err := services.ListProfiles(c.Request.Context(), database.GetDB(), accountID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, profiles)
+}
+
+func CreateProfileForAccount(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	accountID, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account id"})
+		return
+	}
+
+	var payload profilePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := services.GetAccount(c.Request.Context(), database.GetDB(), accountID, userID); err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	profile, err := services.CreateProfile(c.Request.Context(), database.GetDB(), accountID, userID, payload.input())
+	if err != nil {
+		respondProfileError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, profile)
+}
+
+func UpdateProfileForAccount(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	accountID, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account id"})
+		return
+	}
+
+	profileID, err := strconv.ParseInt(c.Param("profileId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid profile id"})
+		return
+	}
+
+	var payload profilePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := services.GetAccount(c.Request.Context(), database.GetDB(), accountID, userID); err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	profile, err := services.UpdateProfile(c.Request.Context(), database.GetDB(), profileID, accountID, userID, payload.input())
+	if err != nil {
+		respondProfileError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+func DeleteProfileForAccount(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	accountID, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account id"})
+		return
+	}
+
+	profileID, err := strconv.ParseInt(c.Param("profileId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid profile id"})
+		return
+	}
+
+	if _, err := services.GetAccount(c.Request.Context(), database.GetDB(), accountID, userID); err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	if err := services.DeleteProfile(c.Request.Context(), database.GetDB(), profileID, accountID, userID); err != nil {
+		respondProfileError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func respondProfileError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "profile not found"})
+	case errors.Is(err, services.ErrInvalidProfile):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrProfileCapacity):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"netflix_central/database"
+	"netflix_central/services"
+)
+
+type launchPayload struct {
+	AccountIDs []int64 `json:"account_ids" binding:"required"`
+}
+
+// LaunchSessions opens several accounts at once, staggered and limited by
+// the session configuration, and reports the result per account.
+func LaunchSessions(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var payload launchPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := services.LaunchSessions(c.Request.Context(), database.GetDB(), userID, payload.AccountIDs)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidLaunch) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// GetLaunchesByAccount lists the account's launch history, newest first.
+func GetLaunchesByAccount(c *gin.Context) {
+	userID, accountID, ok := ownedAccountParam(c)
+	if !ok {
+		return
+	}
+
+	limit := 50
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		limit = n
+	}
+
+	launches, err := services.ListLaunches(c.Request.Context(), database.GetDB(), accountID, userID, limit)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, launches)
+}
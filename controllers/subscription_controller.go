@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"netflix_central/database"
+	"netflix_central/services"
+)
+
+type subscriptionPayload struct {
+	Plan        string  `json:"plan" binding:"required"`
+	Currency    string  `json:"currency" binding:"required"`
+	Amount      float64 `json:"amount"`
+	BillingDay  *int    `json:"billing_day"`
+	NextRenewal string  `json:"next_renewal"`
+	Payer       string  `json:"payer"`
+	Notes       string  `json:"notes"`
+}
+
+func GetSubscriptionForAccount(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	accountID, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account id"})
+		return
+	}
+
+	if _, err := services.GetAccount(c.Request.Context(), database.GetDB(), accountID, userID); err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	sub, err := services.GetSubscription(c.Request.Context(), database.GetDB(), accountID)
+	if err != nil {
+		respondSubscriptionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+func SaveSubscriptionForAccount(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	accountID, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account id"})
+		return
+	}
+
+	var payload subscriptionPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := services.GetAccount(c.Request.Context(), database.GetDB(), accountID, userID); err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	sub, err := services.SaveSubscription(c.Request.Context(), database.GetDB(), accountID, userID, services.SubscriptionInput{
+		Plan:        payload.Plan,
+		Currency:    payload.Currency,
+		Amount:      payload.Amount,
+		BillingDay:  payload.BillingDay,
+		NextRenewal: payload.NextRenewal,
+		Payer:       payload.Payer,
+		Notes:       payload.Notes,
+	})
+	if err != nil {
+		respondSubscriptionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+func DeleteSubscriptionForAccount(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	accountID, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account id"})
+		return
+	}
+
+	if _, err := services.GetAccount(c.Request.Context(), database.GetDB(), accountID, userID); err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	if err := services.DeleteSubscription(c.Request.Context(), database.GetDB(), accountID, userID); err != nil {
+		respondSubscriptionError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func GetReminders(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	reminders, err := services.ListReminders(c.Request.Context(), database.GetDB(), userID, c.Query("all") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, reminders)
+}
+
+func DismissReminder(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reminder id"})
+		return
+	}
+
+	if err := services.DismissReminder(c.Request.Context(), database.GetDB(), id, userID); err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "reminder not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func respondSubscriptionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "subscription not found"})
+	case errors.Is(err, services.ErrInvalidSubscription):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
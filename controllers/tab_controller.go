@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -12,14 +13,26 @@ import (
 )
 
 type tabPayload struct {
-	Title string `json:"title" binding:"required"`
-	URL   string `json:"url" binding:"required"`
+	Title            string `json:"title" binding:"required"`
+	URL              string `json:"url" binding:"required"`
+	Pinned           *bool  `json:"pinned"`
+	Enabled          *bool  `json:"enabled"`
+	OpenInBackground *bool  `json:"open_in_background"`
+	OpenOn           string `json:"open_on"`
+}
+
+func (p tabPayload) options() services.TabOptions {
+	return services.TabOptions{Pinned: p.Pinned, Enabled: p.Enabled, OpenInBackground: p.OpenInBackground, OpenOn: p.OpenOn}
 }
 
 type reorderPayload struct {
 	Order []int64 `json:"order" binding:"required"`
 }
 
+type movePayload struct {
+	Position int `json:"position" binding:"required"`
+}
+
 func GetTabsByAccount(c *gin.Context) {
 	userID, ok := currentUserID(c)
 	if !ok {
@@ -78,11 +91,12 @@ func CreateTabForAccount(c *gin.Context) {
 		return
 	}
 
-	tab, err := services.CreateTab(c.Request.Context(), database.GetDB(), accountID, payload.Title, payload.URL)
+	tab, err := services.CreateTab(c.Request.Context(), database.GetDB(), accountID, userID, payload.Title, payload.URL, payload.options())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondTabError(c, err)
 		return
 	}
+	setETag(c, tab.Version)
 
 	c.JSON(http.StatusCreated, tab)
 }
@@ -121,15 +135,12 @@ func UpdateTabForAccount(c *gin.Context) {
 		return
 	}
 
-	tab, err := services.UpdateTab(c.Request.Context(), database.GetDB(), tabID, accountID, payload.Title, payload.URL)
+	tab, err := services.UpdateTab(c.Request.Context(), database.GetDB(), tabID, accountID, userID, payload.Title, payload.URL, payload.options(), ifMatchVersion(c))
 	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "tab not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondTabError(c, err)
 		return
 	}
+	setETag(c, tab.Version)
 
 	c.JSON(http.StatusOK, tab)
 }
@@ -162,7 +173,7 @@ func DeleteTabForAccount(c *gin.Context) {
 		return
 	}
 
-	if err := services.DeleteTab(c.Request.Context(), database.GetDB(), tabID, accountID); err != nil {
+	if err := services.DeleteTab(c.Request.Context(), database.GetDB(), tabID, accountID, userID); err != nil {
 		if err == sql.ErrNoRows {
 			c.JSON(http.StatusNotFound, gin.H{"error": "tab not found"})
 			return
@@ -202,14 +213,67 @@ func ReorderTabsForAccount(c *gin.Context) {
 		return
 	}
 
-	if err := services.ReorderTabs(c.Request.Context(), database.GetDB(), accountID, payload.Order); err != nil {
+	if err := services.ReorderTabs(c.Request.Context(), database.GetDB(), accountID, userID, payload.Order); err != nil {
+		respondTabError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// MoveTabForAccount moves one tab to a new position, shifting the tabs
+// between its old and new place.
+func MoveTabForAccount(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	accountID, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account id"})
+		return
+	}
+
+	tabID, err := strconv.ParseInt(c.Param("tabId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tab id"})
+		return
+	}
+
+	var payload movePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := services.GetAccount(c.Request.Context(), database.GetDB(), accountID, userID); err != nil {
 		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "tab not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.Status(http.StatusNoContent)
+	tabs, err := services.MoveTab(c.Request.Context(), database.GetDB(), tabID, accountID, userID, payload.Position)
+	if err != nil {
+		respondTabError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tabs)
+}
+
+func respondTabError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "tab not found"})
+	case errors.Is(err, services.ErrInvalidTab):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrVersionMismatch):
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
 }
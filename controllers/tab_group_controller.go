@@ -0,0 +1,209 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"netflix_central/database"
+	"netflix_central/models"
+	"netflix_central/services"
+)
+
+type tabGroupPayload struct {
+	Name      string  `json:"name" binding:"required"`
+	Color     string  `json:"color"`
+	Collapsed bool    `json:"collapsed"`
+	TabIDs    []int64 `json:"tab_ids"`
+}
+
+type tabGroupAssignPayload struct {
+	GroupID *int64 `json:"group_id"`
+}
+
+func (p tabGroupPayload) input() services.TabGroupInput {
+	return services.TabGroupInput{Name: p.Name, Color: p.Color, Collapsed: p.Collapsed}
+}
+
+func GetTabGroupsByAccount(c *gin.Context) {
+	_, accountID, ok := ownedAccountParam(c)
+	if !ok {
+		return
+	}
+
+	groups, err := services.ListTabGroups(c.Request.Context(), database.GetDB(), accountID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, groups)
+}
+
+// CreateTabGroupForAccount adds a group; tab_ids, if given, are moved into it.
+func CreateTabGroupForAccount(c *gin.Context) {
+	userID, accountID, ok := ownedAccountParam(c)
+	if !ok {
+		return
+	}
+
+	var payload tabGroupPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group, err := services.CreateTabGroup(c.Request.Context(), database.GetDB(), accountID, userID, payload.input(), payload.TabIDs)
+	if err != nil {
+		respondTabGroupError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, group)
+}
+
+func UpdateTabGroupForAccount(c *gin.Context) {
+	userID, accountID, ok := ownedAccountParam(c)
+	if !ok {
+		return
+	}
+
+	groupID, err := strconv.ParseInt(c.Param("groupId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group id"})
+		return
+	}
+
+	var payload tabGroupPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group, err := services.UpdateTabGroup(c.Request.Context(), database.GetDB(), groupID, accountID, userID, payload.input())
+	if err != nil {
+		respondTabGroupError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, group)
+}
+
+// DeleteTabGroupForAccount removes a group and ungroups its tabs.
+func DeleteTabGroupForAccount(c *gin.Context) {
+	userID, accountID, ok := ownedAccountParam(c)
+	if !ok {
+		return
+	}
+
+	groupID, err := strconv.ParseInt(c.Param("groupId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group id"})
+		return
+	}
+
+	if err := services.DeleteTabGroup(c.Request.Context(), database.GetDB(), groupID, accountID, userID); err != nil {
+		respondTabGroupError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func ReorderTabGroupsForAccount(c *gin.Context) {
+	userID, accountID, ok := ownedAccountParam(c)
+	if !ok {
+		return
+	}
+
+	var payload reorderPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	groups, err := services.ReorderTabGroups(c.Request.Context(), database.GetDB(), accountID, userID, payload.Order)
+	if err != nil {
+		respondTabGroupError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, groups)
+}
+
+// SetTabGroupForAccount moves a tab into a group, or out of its group when
+// group_id is null.
+func SetTabGroupForAccount(c *gin.Context) {
+	userID, accountID, ok := ownedAccountParam(c)
+	if !ok {
+		return
+	}
+
+	tabID, err := strconv.ParseInt(c.Param("tabId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tab id"})
+		return
+	}
+
+	var payload tabGroupAssignPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tabs, err := services.SetTabGroup(c.Request.Context(), database.GetDB(), tabID, accountID, userID, payload.GroupID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "tab not found"})
+			return
+		}
+		respondTabGroupError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tabs)
+}
+
+// ownedAccountParam reads the :id account parameter and checks that it
+// belongs to the current user, writing the error response when it does not.
+func ownedAccountParam(c *gin.Context) (int64, int64, bool) {
+	account, ok := ownedAccount(c)
+	if !ok {
+		return 0, 0, false
+	}
+	return account.UserID, account.ID, true
+}
+
+// ownedAccount is ownedAccountParam for handlers that need the account itself.
+func ownedAccount(c *gin.Context) (models.Account, bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return models.Account{}, false
+	}
+
+	accountID, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account id"})
+		return models.Account{}, false
+	}
+
+	account, err := services.GetAccount(c.Request.Context(), database.GetDB(), accountID, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
+			return models.Account{}, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return models.Account{}, false
+	}
+	return account, true
+}
+
+func respondTabGroupError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "tab group not found"})
+	case errors.Is(err, services.ErrInvalidTabGroup):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"netflix_central/database"
+	"netflix_central/models"
+	"netflix_central/services"
+)
+
+type tabTemplatePayload struct {
+	Name      string                   `json:"name" binding:"required"`
+	IsDefault bool                     `json:"is_default"`
+	Tabs      []models.TabTemplateItem `json:"tabs" binding:"required"`
+}
+
+func (p tabTemplatePayload) input() services.TabTemplateInput {
+	return services.TabTemplateInput{Name: p.Name, IsDefault: p.IsDefault, Tabs: p.Tabs}
+}
+
+type applyTemplatePayload struct {
+	AccountIDs []int64 `json:"account_ids" binding:"required"`
+	Mode       string  `json:"mode"`
+}
+
+func GetTabTemplates(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	templates, err := services.ListTabTemplates(c.Request.Context(), database.GetDB(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+func GetTabTemplateByID(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid template id"})
+		return
+	}
+
+	tpl, err := services.GetTabTemplate(c.Request.Context(), database.GetDB(), id, userID)
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tpl)
+}
+
+func CreateTabTemplate(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var payload tabTemplatePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tpl, err := services.CreateTabTemplate(c.Request.Context(), database.GetDB(), userID, payload.input())
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, tpl)
+}
+
+func UpdateTabTemplate(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid template id"})
+		return
+	}
+
+	var payload tabTemplatePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tpl, err := services.UpdateTabTemplate(c.Request.Context(), database.GetDB(), id, userID, payload.input())
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tpl)
+}
+
+func DeleteTabTemplate(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid template id"})
+		return
+	}
+
+	if err := services.DeleteTabTemplate(c.Request.Context(), database.GetDB(), id, userID); err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ApplyTabTemplate adds the template's tabs to existing accounts, merging
+// with their tabs (default) or replacing them.
+func ApplyTabTemplate(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid template id"})
+		return
+	}
+
+	var payload applyTemplatePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := services.GetTabTemplate(c.Request.Context(), database.GetDB(), id, userID); err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+
+	results, err := services.ApplyTabTemplate(c.Request.Context(), database.GetDB(), id, userID, payload.AccountIDs, payload.Mode)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
+			return
+		}
+		respondTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"accounts": results})
+}
+
+func respondTemplateError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
+	case errors.Is(err, services.ErrInvalidTemplate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
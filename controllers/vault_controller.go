@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"netflix_central/database"
+	"netflix_central/services"
+)
+
+type passphrasePayload struct {
+	Passphrase string `json:"passphrase" binding:"required"`
+	Minutes    int    `json:"minutes"`
+}
+
+type accountVaultPayload struct {
+	Enabled bool `json:"enabled"`
+}
+
+type secretPayload struct {
+	Value string `json:"value" binding:"required"`
+}
+
+func GetVaultStatus(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	status, err := services.GetVaultStatus(c.Request.Context(), database.GetDB(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+func SetupVault(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var payload passphrasePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.SetupVault(c.Request.Context(), database.GetDB(), userID, payload.Passphrase); err != nil {
+		respondVaultError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func UnlockVault(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var payload passphrasePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status, err := services.UnlockVault(c.Request.Context(), database.GetDB(), userID, payload.Passphrase, payload.Minutes)
+	if err != nil {
+		respondVaultError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+func LockVault(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	services.LockVault(userID)
+	c.Status(http.StatusNoContent)
+}
+
+func SetVaultForAccount(c *gin.Context) {
+	userID, accountID, ok := ownedAccountParam(c)
+	if !ok {
+		return
+	}
+
+	var payload accountVaultPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.SetAccountVault(c.Request.Context(), database.GetDB(), accountID, userID, payload.Enabled); err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	account, err := services.GetAccount(c.Request.Context(), database.GetDB(), accountID, userID)
+	if err != nil {
+		respondAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, account)
+}
+
+func GetSecretsByAccount(c *gin.Context) {
+	_, accountID, ok := ownedAccountParam(c)
+	if !ok {
+		return
+	}
+
+	fields, err := services.ListSecretFields(c.Request.Context(), database.GetDB(), accountID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, fields)
+}
+
+func WriteSecretForAccount(c *gin.Context) {
+	account, ok := ownedAccount(c)
+	if !ok {
+		return
+	}
+
+	var payload secretPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.WriteSecret(c.Request.Context(), database.GetDB(), account, c.Param("field"), payload.Value); err != nil {
+		respondVaultError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func RevealSecretForAccount(c *gin.Context) {
+	account, ok := ownedAccount(c)
+	if !ok {
+		return
+	}
+
+	value, err := services.RevealSecret(c.Request.Context(), database.GetDB(), account, c.Param("field"), services.RevealContext{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	if err != nil {
+		respondVaultError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"field": c.Param("field"), "value": value})
+}
+
+func DeleteSecretForAccount(c *gin.Context) {
+	userID, accountID, ok := ownedAccountParam(c)
+	if !ok {
+		return
+	}
+
+	if err := services.DeleteSecret(c.Request.Context(), database.GetDB(), accountID, userID, c.Param("field")); err != nil {
+		respondVaultError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func GetSecretRevealsByAccount(c *gin.Context) {
+	_, accountID, ok := ownedAccountParam(c)
+	if !ok {
+		return
+	}
+
+	reveals, err := services.ListSecretReveals(c.Request.Context(), database.GetDB(), accountID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, reveals)
+}
+
+func respondVaultError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "secret not found"})
+	case errors.Is(err, services.ErrInvalidSecretField), errors.Is(err, services.ErrVaultDisabled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidPassphrase):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrUnlockThrottled):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrVaultLocked):
+		c.JSON(http.StatusLocked, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrVaultNotSetUp):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrVaultAlreadySetUp):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"netflix_central/database"
+	"netflix_central/services"
+)
+
+// ExportWorkspace streams a zip archive of the caller's accounts, tabs and
+// tags. ?include_profiles=true adds the Chrome profile directories.
+func ExportWorkspace(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	includeProfiles, _ := strconv.ParseBool(c.DefaultQuery("include_profiles", "false"))
+
+	filename := fmt.Sprintf("workspace-%s.zip", time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	if err := services.ExportWorkspace(c.Request.Context(), database.GetDB(), userID, c.Writer, includeProfiles); err != nil {
+		// Headers are already sent; the truncated archive is all we can signal.
+		c.Error(err)
+	}
+}
+
+// ImportWorkspace restores an uploaded workspace archive (multipart field
+// "file"). ?strategy= decides what happens to accounts whose email already
+// exists: skip (default), overwrite or rename.
+func ImportWorkspace(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	strategy := c.DefaultQuery("strategy", services.ConflictSkip)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.WorkspaceImportMaxBytes())
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "archive is too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot read uploaded file"})
+		return
+	}
+	defer f.Close()
+
+	result, err := services.ImportWorkspace(c.Request.Context(), database.GetDB(), userID, f, file.Size, strategy)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidArchive) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -40,6 +40,10 @@ func InitDB() {
 		log.Fatalf("failed to connect to database: %v", err)
 	}
 
+	if err := Migrate(sqlDB); err != nil {
+		log.Fatalf("failed to migrate database: %v", err)
+	}
+
 	db = sqlDB
 }
 
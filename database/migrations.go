@@ -0,0 +1,266 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// migrations are applied in order on every start, so each statement must be idempotent.
+var migrations = []string{
+	`CREATE TABLE IF NOT EXISTS users (
+		id BIGSERIAL PRIMARY KEY,
+		email TEXT NOT NULL UNIQUE,
+		password_hash TEXT NOT NULL
+	);`,
+	`CREATE TABLE IF NOT EXISTS accounts (
+		id BIGSERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id),
+		label TEXT NOT NULL,
+		netflix_email TEXT NOT NULL,
+		status TEXT NOT NULL,
+		chrome_profile TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`,
+	`CREATE TABLE IF NOT EXISTS tabs (
+		id BIGSERIAL PRIMARY KEY,
+		account_id BIGINT NOT NULL REFERENCES accounts(id),
+		title TEXT NOT NULL,
+		url TEXT NOT NULL,
+		position INTEGER NOT NULL
+	);`,
+	`ALTER TABLE accounts ADD COLUMN IF NOT EXISTS last_opened_at TIMESTAMPTZ;`,
+	`CREATE INDEX IF NOT EXISTS accounts_user_created_idx ON accounts (user_id, created_at DESC, id DESC);`,
+	`CREATE TABLE IF NOT EXISTS tags (
+		id BIGSERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id),
+		name TEXT NOT NULL,
+		color TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS tags_user_name_idx ON tags (user_id, LOWER(name));`,
+	`CREATE TABLE IF NOT EXISTS account_tags (
+		account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+		tag_id BIGINT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+		PRIMARY KEY (account_id, tag_id)
+	);`,
+	`CREATE INDEX IF NOT EXISTS account_tags_tag_idx ON account_tags (tag_id);`,
+	`CREATE TABLE IF NOT EXISTS folders (
+		id BIGSERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id),
+		parent_id BIGINT REFERENCES folders(id),
+		name TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`,
+	`ALTER TABLE accounts ADD COLUMN IF NOT EXISTS folder_id BIGINT REFERENCES folders(id);`,
+	`CREATE TABLE IF NOT EXISTS status_history (
+		id BIGSERIAL PRIMARY KEY,
+		account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+		changed_by BIGINT NOT NULL REFERENCES users(id),
+		from_status TEXT NOT NULL,
+		to_status TEXT NOT NULL,
+		note TEXT NOT NULL DEFAULT '',
+		changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`,
+	`CREATE INDEX IF NOT EXISTS status_history_account_idx ON status_history (account_id, changed_at DESC);`,
+	`CREATE TABLE IF NOT EXISTS subscriptions (
+		account_id BIGINT PRIMARY KEY REFERENCES accounts(id) ON DELETE CASCADE,
+		plan TEXT NOT NULL,
+		currency TEXT NOT NULL,
+		amount NUMERIC(12, 2) NOT NULL DEFAULT 0,
+		billing_day INTEGER,
+		next_renewal DATE,
+		payer TEXT NOT NULL DEFAULT '',
+		notes TEXT NOT NULL DEFAULT '',
+		due_soon BOOLEAN NOT NULL DEFAULT FALSE,
+		reminded_for DATE,
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`,
+	`CREATE INDEX IF NOT EXISTS subscriptions_renewal_idx ON subscriptions (next_renewal);`,
+	`CREATE TABLE IF NOT EXISTS reminders (
+		id BIGSERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id),
+		account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+		kind TEXT NOT NULL,
+		due_date DATE NOT NULL,
+		message TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		dismissed_at TIMESTAMPTZ
+	);`,
+	`CREATE INDEX IF NOT EXISTS reminders_user_idx ON reminders (user_id, dismissed_at, created_at DESC);`,
+	`CREATE TABLE IF NOT EXISTS netflix_profiles (
+		id BIGSERIAL PRIMARY KEY,
+		account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		assigned_client TEXT NOT NULL DEFAULT '',
+		pin_protected BOOLEAN NOT NULL DEFAULT FALSE,
+		maturity_level TEXT NOT NULL,
+		notes TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS netflix_profiles_account_name_idx ON netflix_profiles (account_id, LOWER(name));`,
+	`CREATE TABLE IF NOT EXISTS customers (
+		id BIGSERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id),
+		name TEXT NOT NULL,
+		contact TEXT NOT NULL DEFAULT '',
+		notes TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`,
+	`CREATE TABLE IF NOT EXISTS seat_assignments (
+		id BIGSERIAL PRIMARY KEY,
+		customer_id BIGINT NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+		account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+		profile_id BIGINT REFERENCES netflix_profiles(id) ON DELETE SET NULL,
+		starts_on DATE NOT NULL,
+		ends_on DATE,
+		notes TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`,
+	`CREATE INDEX IF NOT EXISTS seat_assignments_account_idx ON seat_assignments (account_id, starts_on);`,
+	`CREATE INDEX IF NOT EXISTS seat_assignments_customer_idx ON seat_assignments (customer_id, starts_on);`,
+	`CREATE TABLE IF NOT EXISTS user_vaults (
+		user_id BIGINT PRIMARY KEY REFERENCES users(id),
+		salt BYTEA NOT NULL,
+		kdf_time INTEGER NOT NULL,
+		kdf_memory INTEGER NOT NULL,
+		kdf_threads INTEGER NOT NULL,
+		check_nonce BYTEA NOT NULL,
+		check_ciphertext BYTEA NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`,
+	`ALTER TABLE accounts ADD COLUMN IF NOT EXISTS vault_enabled BOOLEAN NOT NULL DEFAULT FALSE;`,
+	`CREATE TABLE IF NOT EXISTS account_secrets (
+		account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+		field TEXT NOT NULL,
+		nonce BYTEA NOT NULL,
+		ciphertext BYTEA NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (account_id, field)
+	);`,
+	`CREATE TABLE IF NOT EXISTS secret_reveals (
+		id BIGSERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id),
+		account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+		field TEXT NOT NULL,
+		ip TEXT NOT NULL DEFAULT '',
+		user_agent TEXT NOT NULL DEFAULT '',
+		revealed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`,
+	`CREATE INDEX IF NOT EXISTS secret_reveals_account_idx ON secret_reveals (account_id, revealed_at DESC);`,
+	`CREATE TABLE IF NOT EXISTS audit_events (
+		id BIGSERIAL PRIMARY KEY,
+		user_id BIGINT REFERENCES users(id),
+		actor_email TEXT NOT NULL DEFAULT '',
+		action TEXT NOT NULL,
+		target_type TEXT NOT NULL,
+		target_id BIGINT NOT NULL DEFAULT 0,
+		changes JSONB NOT NULL DEFAULT '{}',
+		ip TEXT NOT NULL DEFAULT '',
+		user_agent TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`,
+	`CREATE INDEX IF NOT EXISTS audit_events_user_idx ON audit_events (user_id, id DESC);`,
+	`CREATE INDEX IF NOT EXISTS audit_events_target_idx ON audit_events (target_type, target_id);`,
+	// The audit log is append-only: updates and deletes are silently dropped.
+	`CREATE OR REPLACE RULE audit_events_no_update AS ON UPDATE TO audit_events DO INSTEAD NOTHING;`,
+	`CREATE OR REPLACE RULE audit_events_no_delete AS ON DELETE TO audit_events DO INSTEAD NOTHING;`,
+	`ALTER TABLE accounts ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;`,
+	`CREATE INDEX IF NOT EXISTS accounts_deleted_idx ON accounts (deleted_at) WHERE deleted_at IS NOT NULL;`,
+	`CREATE TABLE IF NOT EXISTS tab_templates (
+		id BIGSERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id),
+		name TEXT NOT NULL,
+		is_default BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS tab_templates_name_idx ON tab_templates (user_id, LOWER(name));`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS tab_templates_default_idx ON tab_templates (user_id) WHERE is_default;`,
+	`CREATE TABLE IF NOT EXISTS tab_template_items (
+		id BIGSERIAL PRIMARY KEY,
+		template_id BIGINT NOT NULL REFERENCES tab_templates(id) ON DELETE CASCADE,
+		title TEXT NOT NULL,
+		url TEXT NOT NULL,
+		position INTEGER NOT NULL
+	);`,
+	`CREATE INDEX IF NOT EXISTS tab_template_items_template_idx ON tab_template_items (template_id, position);`,
+	`ALTER TABLE accounts ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;`,
+	`ALTER TABLE tabs ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;`,
+	// Close gaps and duplicates left by earlier deletes and racing inserts
+	// before tab positions become unique per account.
+	`UPDATE tabs SET position = r.rn FROM (
+		SELECT id, ROW_NUMBER() OVER (PARTITION BY account_id ORDER BY position, id) AS rn FROM tabs
+	) r WHERE tabs.id = r.id AND tabs.position <> r.rn;`,
+	// Deferred so statements that shift several positions only need to be
+	// consistent at commit.
+	`DO $$ BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'tabs_account_position_key') THEN
+			ALTER TABLE tabs ADD CONSTRAINT tabs_account_position_key UNIQUE (account_id, position) DEFERRABLE INITIALLY DEFERRED;
+		END IF;
+	END $$;`,
+	`CREATE TABLE IF NOT EXISTS tab_groups (
+		id BIGSERIAL PRIMARY KEY,
+		account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		color TEXT NOT NULL DEFAULT 'grey',
+		collapsed BOOLEAN NOT NULL DEFAULT FALSE,
+		position INTEGER NOT NULL
+	);`,
+	`CREATE INDEX IF NOT EXISTS tab_groups_account_idx ON tab_groups (account_id, position);`,
+	`ALTER TABLE tabs ADD COLUMN IF NOT EXISTS group_id BIGINT REFERENCES tab_groups(id) ON DELETE SET NULL;`,
+	`ALTER TABLE tabs ADD COLUMN IF NOT EXISTS pinned BOOLEAN NOT NULL DEFAULT FALSE;`,
+	`ALTER TABLE tabs ADD COLUMN IF NOT EXISTS enabled BOOLEAN NOT NULL DEFAULT TRUE;`,
+	`ALTER TABLE tabs ADD COLUMN IF NOT EXISTS open_in_background BOOLEAN NOT NULL DEFAULT FALSE;`,
+	`ALTER TABLE tabs ADD COLUMN IF NOT EXISTS open_on TEXT NOT NULL DEFAULT 'always';`,
+	`CREATE TABLE IF NOT EXISTS library_tabs (
+		id BIGSERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id),
+		title TEXT NOT NULL,
+		url TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`,
+	`CREATE INDEX IF NOT EXISTS library_tabs_user_idx ON library_tabs (user_id, LOWER(title));`,
+	// A tab linked to a library tab stores only the fields it overrides;
+	// NULL title or url means the library value is used.
+	`ALTER TABLE tabs ADD COLUMN IF NOT EXISTS library_tab_id BIGINT REFERENCES library_tabs(id);`,
+	`CREATE INDEX IF NOT EXISTS tabs_library_tab_idx ON tabs (library_tab_id) WHERE library_tab_id IS NOT NULL;`,
+	`ALTER TABLE tabs ALTER COLUMN title DROP NOT NULL;`,
+	`ALTER TABLE tabs ALTER COLUMN url DROP NOT NULL;`,
+	`DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'tabs_title_url_check') THEN
+			ALTER TABLE tabs ADD CONSTRAINT tabs_title_url_check CHECK (library_tab_id IS NOT NULL OR (title IS NOT NULL AND url IS NOT NULL));
+		END IF;
+	END $$;`,
+	`CREATE TABLE IF NOT EXISTS account_variables (
+		account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		value TEXT NOT NULL,
+		PRIMARY KEY (account_id, name)
+	);`,
+	`CREATE TABLE IF NOT EXISTS launches (
+		id BIGSERIAL PRIMARY KEY,
+		account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+		user_id BIGINT NOT NULL REFERENCES users(id),
+		started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		ended_at TIMESTAMPTZ,
+		outcome TEXT NOT NULL,
+		error TEXT NOT NULL DEFAULT ''
+	);`,
+	`CREATE INDEX IF NOT EXISTS launches_account_idx ON launches (account_id, started_at DESC, id DESC);`,
+	`CREATE INDEX IF NOT EXISTS launches_user_started_idx ON launches (user_id, started_at);`,
+	// Set once the user got the starter "Default" tab template.
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS tab_templates_seeded BOOLEAN NOT NULL DEFAULT FALSE;`,
+}
+
+// Migrate applies every migration to db. InitDB runs it on start; tests use
+// it to prepare their own database.
+func Migrate(db *sql.DB) error {
+	for i, stmt := range migrations {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
@@ -47,8 +47,18 @@ async function request(path, options = {}) {
   return response.json();
 }
 
-export async function fetchAccounts() {
-  return request('/accounts');
+// Returns every matching account. With a limit the server answers in pages,
+// which are followed through next_cursor.
+export async function fetchAccounts(params = {}) {
+  const accounts = [];
+  let cursor = '';
+  do {
+    const query = new URLSearchParams(cursor ? { ...params, cursor } : params).toString();
+    const data = await request(query ? `/accounts?${query}` : '/accounts');
+    accounts.push(...(data?.accounts ?? []));
+    cursor = data?.next_cursor || '';
+  } while (cursor);
+  return accounts;
 }
 
 export async function createAccount(payload) {
@@ -63,8 +73,149 @@ export async function deleteAccount(id) {
   return request(`/accounts/${id}`, { method: 'DELETE' });
 }
 
-export async function openAccount(id) {
-  return request(`/accounts/${id}/open`, { method: 'POST' });
+export async function importAccounts(file, { dryRun = false } = {}) {
+  const form = new FormData();
+  form.append('file', file);
+  const response = await fetch(`${API_BASE}/accounts/import?dry_run=${dryRun}`, {
+    method: 'POST',
+    headers: authToken ? { Authorization: `Bearer ${authToken}` } : {},
+    body: form,
+  });
+  const data = await response.json();
+  if (!response.ok && response.status !== 422) {
+    throw new Error(data?.error || 'Import failed');
+  }
+  return data;
+}
+
+export async function exportWorkspace({ includeProfiles = false } = {}) {
+  const response = await fetch(`${API_BASE}/export?include_profiles=${includeProfiles}`, {
+    headers: authToken ? { Authorization: `Bearer ${authToken}` } : {},
+  });
+  if (!response.ok) {
+    const data = await response.json().catch(() => null);
+    throw new Error(data?.error || 'Export failed');
+  }
+  return response.blob();
+}
+
+export async function importWorkspace(file, { strategy = 'skip' } = {}) {
+  const form = new FormData();
+  form.append('file', file);
+  const response = await fetch(`${API_BASE}/import?strategy=${strategy}`, {
+    method: 'POST',
+    headers: authToken ? { Authorization: `Bearer ${authToken}` } : {},
+    body: form,
+  });
+  const data = await response.json();
+  if (!response.ok) {
+    throw new Error(data?.error || 'Import failed');
+  }
+  return data;
+}
+
+export async function bulkAccounts(payload) {
+  return request('/accounts/bulk', { method: 'POST', body: JSON.stringify(payload) });
+}
+
+export async function fetchLaunches(accountId, { limit = 50 } = {}) {
+  return request(`/accounts/${accountId}/launches?limit=${limit}`);
+}
+
+export async function fetchStats({ days = 7 } = {}) {
+  return request(`/stats?days=${days}`);
+}
+
+export async function fetchAccountVariables(accountId) {
+  const data = await request(`/accounts/${accountId}/variables`);
+  return data?.variables ?? {};
+}
+
+export async function saveAccountVariables(accountId, variables) {
+  return request(`/accounts/${accountId}/variables`, { method: 'PUT', body: JSON.stringify({ variables }) });
+}
+
+export async function fetchTabGroups(accountId) {
+  return request(`/accounts/${accountId}/tab-groups`);
+}
+
+export async function saveTabGroup(accountId, group) {
+  if (group.id) {
+    return request(`/accounts/${accountId}/tab-groups/${group.id}`, { method: 'PUT', body: JSON.stringify(group) });
+  }
+  return request(`/accounts/${accountId}/tab-groups`, { method: 'POST', body: JSON.stringify(group) });
+}
+
+export async function deleteTabGroup(accountId, groupId) {
+  return request(`/accounts/${accountId}/tab-groups/${groupId}`, { method: 'DELETE' });
+}
+
+export async function reorderTabGroups(accountId, order) {
+  return request(`/accounts/${accountId}/tab-groups/reorder`, { method: 'PATCH', body: JSON.stringify({ order }) });
+}
+
+export async function setTabGroup(accountId, tabId, groupId) {
+  return request(`/accounts/${accountId}/tabs/${tabId}/group`, { method: 'PUT', body: JSON.stringify({ group_id: groupId }) });
+}
+
+export async function fetchLibraryTabs() {
+  return request('/library-tabs');
+}
+
+export async function saveLibraryTab(tab) {
+  if (tab.id) {
+    return request(`/library-tabs/${tab.id}`, { method: 'PUT', body: JSON.stringify(tab) });
+  }
+  return request('/library-tabs', { method: 'POST', body: JSON.stringify(tab) });
+}
+
+export async function deleteLibraryTab(id) {
+  return request(`/library-tabs/${id}`, { method: 'DELETE' });
+}
+
+export async function fetchLibraryTabAccounts(id) {
+  return request(`/library-tabs/${id}/accounts`);
+}
+
+export async function attachLibraryTab(id, accountIds) {
+  return request(`/library-tabs/${id}/accounts`, { method: 'POST', body: JSON.stringify({ account_ids: accountIds }) });
+}
+
+export async function fetchTabTemplates() {
+  return request('/tab-templates');
+}
+
+export async function saveTabTemplate(template) {
+  if (template.id) {
+    return request(`/tab-templates/${template.id}`, { method: 'PUT', body: JSON.stringify(template) });
+  }
+  return request('/tab-templates', { method: 'POST', body: JSON.stringify(template) });
+}
+
+export async function deleteTabTemplate(id) {
+  return request(`/tab-templates/${id}`, { method: 'DELETE' });
+}
+
+export async function applyTabTemplate(id, accountIds, mode = 'merge') {
+  return request(`/tab-templates/${id}/apply`, { method: 'POST', body: JSON.stringify({ account_ids: accountIds, mode }) });
+}
+
+export async function fetchTrash() {
+  const data = await request('/accounts/trash');
+  return data?.accounts ?? [];
+}
+
+export async function restoreAccount(id) {
+  return request(`/accounts/${id}/restore`, { method: 'POST' });
+}
+
+export async function launchSessions(accountIds) {
+  return request('/sessions/launch', { method: 'POST', body: JSON.stringify({ account_ids: accountIds }) });
+}
+
+export async function openAccount(id, { tabs } = {}) {
+  const query = tabs?.length ? `?tabs=${tabs.join(',')}` : '';
+  return request(`/accounts/${id}/open${query}`, { method: 'POST' });
 }
 
 export async function register(payload) {
@@ -1,15 +1,27 @@
 package main
 
 import (
+	"context"
 	"log"
 
 	"netflix_central/database"
 	"netflix_central/routes"
+	"netflix_central/services"
 )
 
 func main() {
+	if err := services.LoadBreachedPasswords(); err != nil {
+		log.Fatalf("configuration: %v", err)
+	}
 	database.InitDB()
 
+	if err := services.CloseInterruptedLaunches(context.Background(), database.GetDB()); err != nil {
+		log.Printf("launches: %v", err)
+	}
+
+	go services.RunRenewalScheduler(context.Background(), database.GetDB(), services.LoadRenewalConfig())
+	go services.RunTrashPurger(context.Background(), database.GetDB(), services.LoadTrashConfig())
+
 	router := routes.SetupRouter()
 
 	if err := router.Run(); err != nil {
@@ -8,6 +8,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+
+	"netflix_central/services"
 )
 
 func AuthRequired() gin.HandlerFunc {
@@ -46,10 +48,17 @@ func AuthRequired() gin.HandlerFunc {
 			return
 		}
 
-		if emailVal, ok := claims["email"].(string); ok {
+		emailVal, _ := claims["email"].(string)
+		if emailVal != "" {
 			c.Set("user_email", emailVal)
 		}
 		c.Set("user_id", userID)
+		c.Request = c.Request.WithContext(services.WithActor(c.Request.Context(), services.Actor{
+			UserID:    userID,
+			Email:     emailVal,
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		}))
 		c.Next()
 	}
 }
@@ -3,11 +3,26 @@ package models
 import "time"
 
 type Account struct {
-	ID            int64     `json:"id"`
-	UserID        int64     `json:"-"`
-	Label         string    `json:"label"`
-	NetflixEmail  string    `json:"netflix_email"`
-	ChromeProfile string    `json:"chrome_profile"`
-	Status        string    `json:"status"`
-	CreatedAt     time.Time `json:"created_at"`
+	ID            int64         `json:"id"`
+	UserID        int64         `json:"-"`
+	Label         string        `json:"label"`
+	NetflixEmail  string        `json:"netflix_email"`
+	ChromeProfile string        `json:"chrome_profile"`
+	Status        string        `json:"status"`
+	FolderID      *int64        `json:"folder_id"`
+	VaultEnabled  bool          `json:"vault_enabled"`
+	CreatedAt     time.Time     `json:"created_at"`
+	LastOpenedAt  *time.Time    `json:"last_opened_at"`
+	DeletedAt     *time.Time    `json:"deleted_at,omitempty"`
+	Version       int           `json:"version"`
+	Tags          []Tag         `json:"tags"`
+	Subscription  *Subscription `json:"subscription"`
+	// Profiles is only loaded for the account detail response.
+	Profiles []NetflixProfile `json:"profiles,omitempty"`
+}
+
+// AccountPage is one page of a filtered account listing.
+type AccountPage struct {
+	Accounts   []Account `json:"accounts"`
+	NextCursor string    `json:"next_cursor,omitempty"`
 }
@@ -0,0 +1,22 @@
+package models
+
+// BulkItemResult is the outcome of a bulk action for one account. Warnings
+// are only set by open, see SessionLaunchItem.
+type BulkItemResult struct {
+	ID       int64    `json:"id"`
+	OK       bool     `json:"ok"`
+	Error    string   `json:"error,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// BulkResult reports a bulk action. With atomic set, a single failure rolls
+// every item back and RolledBack is true.
+type BulkResult struct {
+	Action     string           `json:"action"`
+	Atomic     bool             `json:"atomic"`
+	Total      int              `json:"total"`
+	Succeeded  int              `json:"succeeded"`
+	Failed     int              `json:"failed"`
+	RolledBack bool             `json:"rolled_back"`
+	Items      []BulkItemResult `json:"items"`
+}
@@ -0,0 +1,22 @@
+package models
+
+// ImportRowResult reports the outcome of one row of an account import.
+// Row is 1-based and counts data rows only, not the CSV header.
+type ImportRowResult struct {
+	Row       int      `json:"row"`
+	Label     string   `json:"label"`
+	Email     string   `json:"email"`
+	Errors    []string `json:"errors,omitempty"`
+	AccountID *int64   `json:"account_id,omitempty"`
+}
+
+// ImportResult summarises an account import. In a dry run, or when any row
+// is invalid, nothing is written and Created is zero.
+type ImportResult struct {
+	DryRun  bool              `json:"dry_run"`
+	Total   int               `json:"total"`
+	Valid   int               `json:"valid"`
+	Created int               `json:"created"`
+	NewTags []string          `json:"new_tags"`
+	Rows    []ImportRowResult `json:"rows"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEvent is one append-only entry of the audit log. Changes maps each
+// modified field to its previous and new value.
+type AuditEvent struct {
+	ID         int64           `json:"id"`
+	UserID     *int64          `json:"user_id"`
+	ActorEmail string          `json:"actor_email"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   int64           `json:"target_id"`
+	Changes    json.RawMessage `json:"changes"`
+	IP         string          `json:"ip"`
+	UserAgent  string          `json:"user_agent"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Customer is a client who rents seats on managed Netflix accounts.
+type Customer struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"-"`
+	Name      string    `json:"name"`
+	Contact   string    `json:"contact"`
+	Notes     string    `json:"notes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SeatAssignment links a customer to an account, optionally to one of its
+// household profiles, for a period of time. A nil EndsOn means open-ended.
+type SeatAssignment struct {
+	ID           int64      `json:"id"`
+	CustomerID   int64      `json:"customer_id"`
+	CustomerName string     `json:"customer_name"`
+	AccountID    int64      `json:"account_id"`
+	AccountLabel string     `json:"account_label"`
+	ProfileID    *int64     `json:"profile_id"`
+	ProfileName  string     `json:"profile_name,omitempty"`
+	StartsOn     time.Time  `json:"starts_on"`
+	EndsOn       *time.Time `json:"ends_on"`
+	Notes        string     `json:"notes"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// FreeSeats summarises seat usage of one account on a given day.
+type FreeSeats struct {
+	AccountID    int64  `json:"account_id"`
+	AccountLabel string `json:"account_label"`
+	Plan         string `json:"plan"`
+	Capacity     int    `json:"capacity"`
+	Occupied     int    `json:"occupied"`
+	Free         int    `json:"free"`
+}
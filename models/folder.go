@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Folder groups accounts, e.g. per customer or region. Folders can be nested.
+type Folder struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"-"`
+	ParentID  *int64    `json:"parent_id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
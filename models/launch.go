@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Launch outcomes.
+const (
+	LaunchRunning     = "running"
+	LaunchExited      = "exited"
+	LaunchReused      = "reused"
+	LaunchFailed      = "failed"
+	LaunchInterrupted = "interrupted"
+)
+
+// Launch is one attempt to open a Chrome session for an account. Duration
+// is known once a browser started by the server exits; a launch that only
+// opened a window in an already running browser is "reused" and has none.
+type Launch struct {
+	ID              int64      `json:"id"`
+	AccountID       int64      `json:"account_id"`
+	UserID          int64      `json:"user_id"`
+	UserEmail       string     `json:"user_email"`
+	StartedAt       time.Time  `json:"started_at"`
+	EndedAt         *time.Time `json:"ended_at"`
+	DurationSeconds *int64     `json:"duration_seconds"`
+	Outcome         string     `json:"outcome"`
+	Error           string     `json:"error,omitempty"`
+}
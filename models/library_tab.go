@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// LibraryTab is a tab shared by reference: accounts that link it open its
+// current title and URL unless they override them.
+type LibraryTab struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"-"`
+	Title      string    `json:"title"`
+	URL        string    `json:"url"`
+	UsageCount int       `json:"usage_count"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// LibraryTabUsage is one account tab linked to a library tab.
+type LibraryTabUsage struct {
+	AccountID       int64  `json:"account_id"`
+	AccountLabel    string `json:"account_label"`
+	NetflixEmail    string `json:"netflix_email"`
+	TabID           int64  `json:"tab_id"`
+	TitleOverridden bool   `json:"title_overridden"`
+	URLOverridden   bool   `json:"url_overridden"`
+}
+
+// LibraryTabAttachResult reports linking a library tab to one account.
+// Skipped is set when the account already had the tab.
+type LibraryTabAttachResult struct {
+	AccountID int64 `json:"account_id"`
+	TabID     int64 `json:"tab_id"`
+	Skipped   bool  `json:"skipped"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// NetflixProfile is one viewer profile inside a Netflix account's household.
+type NetflixProfile struct {
+	ID             int64     `json:"id"`
+	AccountID      int64     `json:"account_id"`
+	Name           string    `json:"name"`
+	AssignedClient string    `json:"assigned_client"`
+	PinProtected   bool      `json:"pin_protected"`
+	MaturityLevel  string    `json:"maturity_level"`
+	Notes          string    `json:"notes"`
+	CreatedAt      time.Time `json:"created_at"`
+}
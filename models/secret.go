@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// SecretField describes a stored vault entry without revealing its value.
+type SecretField struct {
+	Field     string    `json:"field"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SecretReveal records one decryption of a vault entry.
+type SecretReveal struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"user_id"`
+	AccountID  int64     `json:"account_id"`
+	Field      string    `json:"field"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	RevealedAt time.Time `json:"revealed_at"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// SessionLaunchItem is the outcome of launching one account. Warnings name
+// tab options that were not applied to a launched session.
+type SessionLaunchItem struct {
+	AccountID int64      `json:"account_id"`
+	OK        bool       `json:"ok"`
+	Error     string     `json:"error,omitempty"`
+	Warnings  []string   `json:"warnings,omitempty"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+}
+
+// SessionLaunchResult reports a batch launch. Running is the number of
+// sessions open after the batch, out of MaxConcurrent.
+type SessionLaunchResult struct {
+	Total         int                 `json:"total"`
+	Launched      int                 `json:"launched"`
+	Failed        int                 `json:"failed"`
+	Running       int                 `json:"running"`
+	MaxConcurrent int                 `json:"max_concurrent"`
+	Items         []SessionLaunchItem `json:"items"`
+}
@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// Stats summarizes a user's accounts and their launches over the last Days
+// days, counting today.
+type Stats struct {
+	GeneratedAt time.Time     `json:"generated_at"`
+	Days        int           `json:"days"`
+	Accounts    AccountStats  `json:"accounts"`
+	Launches    LaunchStats   `json:"launches"`
+	Operators   []OperatorUse `json:"operators"`
+}
+
+// AccountStats counts the accounts that are not in the trash.
+type AccountStats struct {
+	Total     int            `json:"total"`
+	ByStatus  map[string]int `json:"by_status"`
+	ByTag     []TagCount     `json:"by_tag"`
+	StaleDays int            `json:"stale_days"`
+	Stale     int            `json:"stale"`
+	// StalestAccounts are the accounts left unopened the longest, never
+	// opened ones first.
+	StalestAccounts []StaleAccount `json:"stalest_accounts"`
+}
+
+// TagCount is the number of accounts carrying a tag.
+type TagCount struct {
+	TagID    int64  `json:"tag_id"`
+	Name     string `json:"name"`
+	Color    string `json:"color"`
+	Accounts int    `json:"accounts"`
+}
+
+// StaleAccount is an account not opened within the stale threshold.
+type StaleAccount struct {
+	ID           int64      `json:"id"`
+	Label        string     `json:"label"`
+	Status       string     `json:"status"`
+	LastOpenedAt *time.Time `json:"last_opened_at"`
+}
+
+// LaunchStats counts launches in the period. AvgSessionSeconds only covers
+// sessions whose browser exited, the only ones with a known duration.
+type LaunchStats struct {
+	Total             int        `json:"total"`
+	PerDay            []DayCount `json:"per_day"`
+	AvgSessionSeconds *float64   `json:"avg_session_seconds"`
+}
+
+// DayCount is the number of launches on one day (YYYY-MM-DD).
+type DayCount struct {
+	Date     string `json:"date"`
+	Launches int    `json:"launches"`
+}
+
+// OperatorUse is what one operator launched in the period, busiest account
+// first.
+type OperatorUse struct {
+	UserID            int64        `json:"user_id"`
+	Email             string       `json:"email"`
+	Launches          int          `json:"launches"`
+	AvgSessionSeconds *float64     `json:"avg_session_seconds"`
+	Accounts          []AccountUse `json:"accounts"`
+}
+
+// AccountUse is how often an operator launched one account.
+type AccountUse struct {
+	AccountID      int64     `json:"account_id"`
+	Label          string    `json:"label"`
+	Launches       int       `json:"launches"`
+	LastLaunchedAt time.Time `json:"last_launched_at"`
+}
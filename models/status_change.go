@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// StatusChange is one entry in an account's status history.
+type StatusChange struct {
+	ID             int64     `json:"id"`
+	AccountID      int64     `json:"account_id"`
+	ChangedBy      int64     `json:"changed_by"`
+	ChangedByEmail string    `json:"changed_by_email"`
+	FromStatus     string    `json:"from_status"`
+	ToStatus       string    `json:"to_status"`
+	Note           string    `json:"note"`
+	ChangedAt      time.Time `json:"changed_at"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Subscription holds the plan and billing details of a Netflix account.
+type Subscription struct {
+	AccountID   int64      `json:"account_id"`
+	Plan        string     `json:"plan"`
+	Currency    string     `json:"currency"`
+	Amount      float64    `json:"amount"`
+	BillingDay  *int       `json:"billing_day"`
+	NextRenewal *time.Time `json:"next_renewal"`
+	Payer       string     `json:"payer"`
+	Notes       string     `json:"notes"`
+	DueSoon     bool       `json:"due_soon"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Reminder is an event raised for the user, e.g. an upcoming renewal.
+type Reminder struct {
+	ID          int64      `json:"id"`
+	AccountID   int64      `json:"account_id"`
+	Kind        string     `json:"kind"`
+	DueDate     time.Time  `json:"due_date"`
+	Message     string     `json:"message"`
+	CreatedAt   time.Time  `json:"created_at"`
+	DismissedAt *time.Time `json:"dismissed_at"`
+}
@@ -1,10 +1,27 @@
 package models
 
-// Tab represents a saved tab for an account.
+// Tab open_on values.
+const (
+	TabOpenAlways      = "always"
+	TabOpenFirstLaunch = "first-launch"
+	TabOpenManual      = "manual"
+)
+
+// Tab represents a saved tab for an account. A tab linked to a library tab
+// shows the library's title and URL unless the account overrides them.
 type Tab struct {
-	ID        int64  `json:"id"`
-	AccountID int64  `json:"account_id"`
-	Title     string `json:"title"`
-	URL       string `json:"url"`
-	Position  int    `json:"position"`
+	ID               int64  `json:"id"`
+	AccountID        int64  `json:"account_id"`
+	Title            string `json:"title"`
+	URL              string `json:"url"`
+	LibraryTabID     *int64 `json:"library_tab_id"`
+	TitleOverridden  bool   `json:"title_overridden"`
+	URLOverridden    bool   `json:"url_overridden"`
+	GroupID          *int64 `json:"group_id"`
+	Pinned           bool   `json:"pinned"`
+	Enabled          bool   `json:"enabled"`
+	OpenInBackground bool   `json:"open_in_background"`
+	OpenOn           string `json:"open_on"`
+	Position         int    `json:"position"`
+	Version          int    `json:"version"`
 }
@@ -0,0 +1,13 @@
+package models
+
+// TabGroup mirrors a Chrome tab group. Its tabs are always contiguous in
+// the account's tab order; TabIDs lists them in that order.
+type TabGroup struct {
+	ID        int64   `json:"id"`
+	AccountID int64   `json:"account_id"`
+	Name      string  `json:"name"`
+	Color     string  `json:"color"`
+	Collapsed bool    `json:"collapsed"`
+	Position  int     `json:"position"`
+	TabIDs    []int64 `json:"tab_ids"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// TabTemplate is a named set of tabs a user can give new accounts or apply
+// to existing ones. At most one template per user is the default.
+type TabTemplate struct {
+	ID        int64             `json:"id"`
+	UserID    int64             `json:"-"`
+	Name      string            `json:"name"`
+	IsDefault bool              `json:"is_default"`
+	Tabs      []TabTemplateItem `json:"tabs"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+type TabTemplateItem struct {
+	Title    string `json:"title"`
+	URL      string `json:"url"`
+	Position int    `json:"position"`
+}
+
+// TemplateApplyResult reports how applying a template changed one account.
+type TemplateApplyResult struct {
+	AccountID int64 `json:"account_id"`
+	Added     int   `json:"added"`
+	Removed   int   `json:"removed"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Tag is a user-defined label that can be attached to many accounts.
+type Tag struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"-"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color"`
+	CreatedAt time.Time `json:"created_at"`
+}
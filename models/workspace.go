@@ -0,0 +1,92 @@
+package models
+
+import "time"
+
+// WorkspaceManifestVersion is the manifest format written by the export.
+// Version 2 added tab flags, tab groups, library tabs and account variables;
+// version 1 manifests are still read, see WorkspaceManifest.
+const WorkspaceManifestVersion = 2
+
+// WorkspaceManifest is the manifest.json at the root of a workspace archive.
+// IDs are those of the exporting instance and are remapped on import. A
+// version 1 manifest has no library tabs, groups or variables, and its tabs
+// are imported enabled and opened on every launch.
+type WorkspaceManifest struct {
+	Version     int                   `json:"version"`
+	ExportedAt  time.Time             `json:"exported_at"`
+	Profiles    bool                  `json:"profiles"`
+	Tags        []WorkspaceTag        `json:"tags"`
+	LibraryTabs []WorkspaceLibraryTab `json:"library_tabs"`
+	Accounts    []WorkspaceAccount    `json:"accounts"`
+}
+
+type WorkspaceTag struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type WorkspaceLibraryTab struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+type WorkspaceAccount struct {
+	ID            int64               `json:"id"`
+	Label         string              `json:"label"`
+	NetflixEmail  string              `json:"netflix_email"`
+	Status        string              `json:"status"`
+	ChromeProfile string              `json:"chrome_profile"`
+	CreatedAt     time.Time           `json:"created_at"`
+	TagIDs        []int64             `json:"tag_ids"`
+	Variables     map[string]string   `json:"variables"`
+	TabGroups     []WorkspaceTabGroup `json:"tab_groups"`
+	Tabs          []WorkspaceTab      `json:"tabs"`
+}
+
+type WorkspaceTabGroup struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Color     string `json:"color"`
+	Collapsed bool   `json:"collapsed"`
+	Position  int    `json:"position"`
+}
+
+// WorkspaceTab holds a tab's effective title and URL. For a tab linked to a
+// library tab the overridden flags tell which of them the account set itself.
+type WorkspaceTab struct {
+	Title            string `json:"title"`
+	URL              string `json:"url"`
+	Position         int    `json:"position"`
+	LibraryTabID     *int64 `json:"library_tab_id"`
+	TitleOverridden  bool   `json:"title_overridden"`
+	URLOverridden    bool   `json:"url_overridden"`
+	GroupID          *int64 `json:"group_id"`
+	Pinned           bool   `json:"pinned"`
+	Enabled          bool   `json:"enabled"`
+	OpenInBackground bool   `json:"open_in_background"`
+	OpenOn           string `json:"open_on"`
+}
+
+// WorkspaceImportItem records what happened to one account of the archive.
+// Action is one of created, overwritten, renamed or skipped.
+type WorkspaceImportItem struct {
+	SourceID  int64  `json:"source_id"`
+	AccountID int64  `json:"account_id,omitempty"`
+	Label     string `json:"label"`
+	Action    string `json:"action"`
+}
+
+// WorkspaceImportResult summarises a workspace import.
+type WorkspaceImportResult struct {
+	Strategy           string                `json:"strategy"`
+	Created            int                   `json:"created"`
+	Overwritten        int                   `json:"overwritten"`
+	Renamed            int                   `json:"renamed"`
+	Skipped            int                   `json:"skipped"`
+	TagsCreated        int                   `json:"tags_created"`
+	LibraryTabsCreated int                   `json:"library_tabs_created"`
+	ProfilesRestored   int                   `json:"profiles_restored"`
+	Accounts           []WorkspaceImportItem `json:"accounts"`
+}
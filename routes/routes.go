@@ -22,15 +22,133 @@ func SetupRouter() *gin.Engine {
 	{
 		accounts.GET("", controllers.GetAccounts)
 		accounts.POST("", controllers.CreateAccount)
+		accounts.GET("/trash", controllers.GetTrashedAccounts)
+		accounts.POST("/import", controllers.ImportAccounts)
+		accounts.POST("/bulk", controllers.BulkAccounts)
 		accounts.GET("/:id", controllers.GetAccountByID)
 		accounts.PUT("/:id", controllers.UpdateAccount)
 		accounts.DELETE("/:id", controllers.DeleteAccount)
+		accounts.POST("/:id/restore", controllers.RestoreAccount)
+		accounts.PATCH("/:id/status", controllers.ChangeAccountStatus)
+		accounts.GET("/:id/status-history", controllers.GetAccountStatusHistory)
 		accounts.POST("/:id/open", controllers.OpenAccountSession)
+		accounts.GET("/:id/launches", controllers.GetLaunchesByAccount)
 		accounts.GET("/:id/tabs", controllers.GetTabsByAccount)
 		accounts.POST("/:id/tabs", controllers.CreateTabForAccount)
 		accounts.PUT("/:id/tabs/:tabId", controllers.UpdateTabForAccount)
 		accounts.DELETE("/:id/tabs/:tabId", controllers.DeleteTabForAccount)
 		accounts.PATCH("/:id/tabs/reorder", controllers.ReorderTabsForAccount)
+		accounts.PATCH("/:id/tabs/:tabId/position", controllers.MoveTabForAccount)
+		accounts.PUT("/:id/tabs/:tabId/group", controllers.SetTabGroupForAccount)
+		accounts.GET("/:id/tab-groups", controllers.GetTabGroupsByAccount)
+		accounts.POST("/:id/tab-groups", controllers.CreateTabGroupForAccount)
+		accounts.PATCH("/:id/tab-groups/reorder", controllers.ReorderTabGroupsForAccount)
+		accounts.PUT("/:id/tab-groups/:groupId", controllers.UpdateTabGroupForAccount)
+		accounts.DELETE("/:id/tab-groups/:groupId", controllers.DeleteTabGroupForAccount)
+		accounts.GET("/:id/variables", controllers.GetVariablesByAccount)
+		accounts.PUT("/:id/variables", controllers.SetVariablesForAccount)
+		accounts.PUT("/:id/tags", controllers.SetTagsForAccount)
+		accounts.POST("/:id/tags/:tagId", controllers.AddTagToAccount)
+		accounts.DELETE("/:id/tags/:tagId", controllers.RemoveTagFromAccount)
+		accounts.PUT("/:id/folder", controllers.SetFolderForAccount)
+		accounts.GET("/:id/subscription", controllers.GetSubscriptionForAccount)
+		accounts.PUT("/:id/subscription", controllers.SaveSubscriptionForAccount)
+		accounts.DELETE("/:id/subscription", controllers.DeleteSubscriptionForAccount)
+		accounts.GET("/:id/profiles", controllers.GetProfilesByAccount)
+		accounts.POST("/:id/profiles", controllers.CreateProfileForAccount)
+		accounts.PUT("/:id/profiles/:profileId", controllers.UpdateProfileForAccount)
+		accounts.DELETE("/:id/profiles/:profileId", controllers.DeleteProfileForAccount)
+		accounts.GET("/:id/occupants", controllers.GetOccupantsByAccount)
+		accounts.PUT("/:id/vault", controllers.SetVaultForAccount)
+		accounts.GET("/:id/secrets", controllers.GetSecretsByAccount)
+		accounts.PUT("/:id/secrets/:field", controllers.WriteSecretForAccount)
+		accounts.POST("/:id/secrets/:field/reveal", controllers.RevealSecretForAccount)
+		accounts.DELETE("/:id/secrets/:field", controllers.DeleteSecretForAccount)
+		accounts.GET("/:id/secret-reveals", controllers.GetSecretRevealsByAccount)
+	}
+
+	protected.GET("/export", controllers.ExportWorkspace)
+	protected.POST("/import", controllers.ImportWorkspace)
+	protected.GET("/stats", controllers.GetStats)
+
+	sessions := protected.Group("/sessions")
+	{
+		sessions.POST("/launch", controllers.LaunchSessions)
+	}
+
+	vault := protected.Group("/vault")
+	{
+		vault.GET("", controllers.GetVaultStatus)
+		vault.POST("/setup", controllers.SetupVault)
+		vault.POST("/unlock", controllers.UnlockVault)
+		vault.POST("/lock", controllers.LockVault)
+	}
+
+	customers := protected.Group("/customers")
+	{
+		customers.GET("", controllers.GetCustomers)
+		customers.POST("", controllers.CreateCustomer)
+		customers.GET("/:id", controllers.GetCustomerByID)
+		customers.PUT("/:id", controllers.UpdateCustomer)
+		customers.DELETE("/:id", controllers.DeleteCustomer)
+		customers.GET("/:id/seats", controllers.GetSeatsByCustomer)
+	}
+
+	seats := protected.Group("/seats")
+	{
+		seats.POST("", controllers.CreateSeat)
+		seats.GET("/free", controllers.GetFreeSeats)
+		seats.PUT("/:id", controllers.UpdateSeat)
+		seats.DELETE("/:id", controllers.DeleteSeat)
+	}
+
+	audit := protected.Group("/audit")
+	{
+		audit.GET("", controllers.GetAuditEvents)
+		audit.GET("/export", controllers.ExportAuditEvents)
+	}
+
+	reminders := protected.Group("/reminders")
+	{
+		reminders.GET("", controllers.GetReminders)
+		reminders.POST("/:id/dismiss", controllers.DismissReminder)
+	}
+
+	tags := protected.Group("/tags")
+	{
+		tags.GET("", controllers.GetTags)
+		tags.POST("", controllers.CreateTag)
+		tags.PUT("/:id", controllers.UpdateTag)
+		tags.DELETE("/:id", controllers.DeleteTag)
+	}
+
+	tabTemplates := protected.Group("/tab-templates")
+	{
+		tabTemplates.GET("", controllers.GetTabTemplates)
+		tabTemplates.POST("", controllers.CreateTabTemplate)
+		tabTemplates.GET("/:id", controllers.GetTabTemplateByID)
+		tabTemplates.PUT("/:id", controllers.UpdateTabTemplate)
+		tabTemplates.DELETE("/:id", controllers.DeleteTabTemplate)
+		tabTemplates.POST("/:id/apply", controllers.ApplyTabTemplate)
+	}
+
+	libraryTabs := protected.Group("/library-tabs")
+	{
+		libraryTabs.GET("", controllers.GetLibraryTabs)
+		libraryTabs.POST("", controllers.CreateLibraryTab)
+		libraryTabs.GET("/:id", controllers.GetLibraryTabByID)
+		libraryTabs.PUT("/:id", controllers.UpdateLibraryTab)
+		libraryTabs.DELETE("/:id", controllers.DeleteLibraryTab)
+		libraryTabs.GET("/:id/accounts", controllers.GetLibraryTabAccounts)
+		libraryTabs.POST("/:id/accounts", controllers.AttachLibraryTab)
+	}
+
+	folders := protected.Group("/folders")
+	{
+		folders.GET("", controllers.GetFolders)
+		folders.POST("", controllers.CreateFolder)
+		folders.PUT("/:id", controllers.UpdateFolder)
+		folders.DELETE("/:id", controllers.DeleteFolder)
 	}
 	return router
 }
@@ -39,7 +157,8 @@ func cors() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,DELETE,PATCH,OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-Match")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "ETag")
 
 		if c.Request.Method == http.MethodOptions {
 			c.AbortWithStatus(http.StatusNoContent)
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"netflix_central/models"
+)
+
+var ErrInvalidBulk = errors.New("invalid bulk request")
+
+const maxBulkAccounts = 500
+
+// Bulk actions accepted by RunBulkAction.
+const (
+	BulkSetStatus = "set-status"
+	BulkAddTag    = "add-tag"
+	BulkRemoveTag = "remove-tag"
+	BulkDelete    = "delete"
+	BulkOpen      = "open"
+)
+
+// errBulkRollback aborts an atomic bulk transaction after an item failed.
+var errBulkRollback = errors.New("bulk action rolled back")
+
+// BulkAction describes one action applied to many accounts. The accounts
+// are given either by IDs or by Filter, which takes the same options as
+// ListAccounts (sorting and paging are ignored).
+type BulkAction struct {
+	Action string
+	IDs    []int64
+	Filter *AccountQuery
+	Status string
+	Note   string
+	TagID  int64
+	Atomic bool
+}
+
+// RunBulkAction applies the action to every selected account and reports
+// each item. Database actions run in one transaction with a savepoint per
+// item: by default failed items are skipped and the rest is committed, with
+// Atomic any failure rolls everything back. Opening accounts launches
+// processes and is not transactional.
+func RunBulkAction(ctx context.Context, db *sql.DB, userID int64, action BulkAction) (models.BulkResult, error) {
+	result := models.BulkResult{Action: action.Action, Atomic: action.Atomic, Items: []models.BulkItemResult{}}
+
+	var apply func(tx *sql.Tx, id int64) error
+	switch action.Action {
+	case BulkSetStatus:
+		to := normalizeStatus(action.Status)
+		if to == "" {
+			return result, fmt.Errorf("%w: %q", ErrInvalidStatus, action.Status)
+		}
+		apply = func(tx *sql.Tx, id int64) error {
+			from, err := transitionStatusTx(ctx, tx, id, userID, to, action.Note)
+			if err != nil || from == to {
+				return err
+			}
+			return recordAudit(ctx, tx, "account.status", "account", id, map[string]any{"status": from}, map[string]any{"status": to, "note": action.Note})
+		}
+	case BulkAddTag, BulkRemoveTag:
+		if action.TagID <= 0 {
+			return result, fmt.Errorf("%w: tag_id is required", ErrInvalidBulk)
+		}
+		if _, err := GetTag(ctx, db, action.TagID, userID); err != nil {
+			return result, err
+		}
+		apply = func(tx *sql.Tx, id int64) error {
+			if action.Action == BulkAddTag {
+				return addAccountTagTx(ctx, tx, id, action.TagID, userID)
+			}
+			err := removeAccountTagTx(ctx, tx, id, action.TagID, userID)
+			if !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			// The tag was not attached; only an unknown account is a failure.
+			return lockOwnedAccount(ctx, tx, id, userID)
+		}
+	case BulkDelete:
+		apply = func(tx *sql.Tx, id int64) error {
+			return deleteAccountTx(ctx, tx, id, userID)
+		}
+	case BulkOpen:
+		if action.Atomic {
+			return result, fmt.Errorf("%w: open cannot be atomic", ErrInvalidBulk)
+		}
+	default:
+		return result, fmt.Errorf("%w: action must be one of set-status, add-tag, remove-tag, delete, open", ErrInvalidBulk)
+	}
+
+	ids, err := resolveBulkIDs(ctx, db, userID, action)
+	if err != nil {
+		return result, err
+	}
+	result.Total = len(ids)
+	result.Items = make([]models.BulkItemResult, len(ids))
+	for i, id := range ids {
+		result.Items[i].ID = id
+	}
+
+	if action.Action == BulkOpen {
+		for i, id := range ids {
+			warnings, err := OpenAccount(ctx, db, id, userID, nil)
+			result.Items[i].Warnings = warnings
+			setBulkItem(&result, i, err)
+		}
+		return result, nil
+	}
+
+	err = withTx(ctx, db, func(tx *sql.Tx) error {
+		for i, id := range ids {
+			if _, err := tx.ExecContext(ctx, `SAVEPOINT bulk_item;`); err != nil {
+				return fmt.Errorf("savepoint: %w", err)
+			}
+			itemErr := apply(tx, id)
+			if itemErr != nil {
+				if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT bulk_item;`); err != nil {
+					return fmt.Errorf("rollback savepoint: %w", err)
+				}
+			} else if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT bulk_item;`); err != nil {
+				return fmt.Errorf("release savepoint: %w", err)
+			}
+			setBulkItem(&result, i, itemErr)
+		}
+		if action.Atomic && result.Failed > 0 {
+			return errBulkRollback
+		}
+		return nil
+	})
+	if errors.Is(err, errBulkRollback) {
+		result.RolledBack = true
+		result.Succeeded = 0
+		for i := range result.Items {
+			result.Items[i].OK = false
+		}
+		return result, nil
+	}
+	return result, err
+}
+
+// resolveBulkIDs returns the distinct account ids the action applies to.
+// Explicit ids are checked per item, so unknown ones show up in the report.
+func resolveBulkIDs(ctx context.Context, db *sql.DB, userID int64, action BulkAction) ([]int64, error) {
+	if (len(action.IDs) == 0) == (action.Filter == nil) {
+		return nil, fmt.Errorf("%w: give either ids or a filter", ErrInvalidBulk)
+	}
+
+	var ids []int64
+	if action.Filter != nil {
+		q := *action.Filter
+		q.Sort, q.Order, q.Cursor, q.Limit = "", "", "", 0
+		page, err := ListAccounts(ctx, db, userID, q)
+		if err != nil {
+			return nil, err
+		}
+		for _, acc := range page.Accounts {
+			ids = append(ids, acc.ID)
+		}
+	} else {
+		ids = uniqueIDs(action.IDs)
+	}
+
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("%w: no accounts selected", ErrInvalidBulk)
+	}
+	if len(ids) > maxBulkAccounts {
+		return nil, fmt.Errorf("%w: %d accounts selected, at most %d per request", ErrInvalidBulk, len(ids), maxBulkAccounts)
+	}
+	return ids, nil
+}
+
+func setBulkItem(result *models.BulkResult, i int, err error) {
+	switch {
+	case err == nil:
+		result.Items[i].OK = true
+		result.Succeeded++
+		return
+	case errors.Is(err, sql.ErrNoRows):
+		result.Items[i].Error = "account not found"
+	default:
+		result.Items[i].Error = err.Error()
+	}
+	result.Failed++
+}
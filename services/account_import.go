@@ -0,0 +1,428 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"netflix_central/models"
+)
+
+var ErrInvalidImport = errors.New("invalid import")
+
+// errImportConflict aborts an import whose rows became invalid through a
+// change committed after they were validated.
+var errImportConflict = errors.New("import conflicts with a concurrent change")
+
+const maxImportRows = 1000
+
+// ImportRow is one account to import. Tags are referenced by name and are
+// created when the user does not have them yet.
+type ImportRow struct {
+	Label  string      `json:"label"`
+	Email  string      `json:"email"`
+	Status string      `json:"status"`
+	Tags   []string    `json:"tags"`
+	Tabs   []ImportTab `json:"tabs"`
+}
+
+// ImportTab is an extra tab added after those of the default template.
+type ImportTab struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// ParseImportJSON reads either a JSON array of rows or an object with an
+// "accounts" array.
+func ParseImportJSON(r io.Reader) ([]ImportRow, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []ImportRow
+	if err := json.Unmarshal(raw, &rows); err != nil {
+		var wrapped struct {
+			Accounts []ImportRow `json:"accounts"`
+		}
+		if err := json.Unmarshal(raw, &wrapped); err != nil {
+			return nil, fmt.Errorf("%w: body is not a JSON array of accounts", ErrInvalidImport)
+		}
+		rows = wrapped.Accounts
+	}
+	return checkImportSize(rows)
+}
+
+// ParseImportCSV reads a CSV file with a header row. The label and email
+// columns are required; status, tags and tabs are optional. Tags are separated
+// by ";". Tabs go one per line of a quoted field, since URLs may contain ";",
+// each written as "Title|URL" or just "URL".
+func ParseImportCSV(r io.Reader) ([]ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing CSV header", ErrInvalidImport)
+	}
+	cols := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if name == "netflix_email" {
+			name = "email"
+		}
+		cols[name] = i
+	}
+	for _, required := range []string{"label", "email"} {
+		if _, ok := cols[required]; !ok {
+			return nil, fmt.Errorf("%w: CSV header must contain a %s column", ErrInvalidImport, required)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := cols[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var rows []ImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+
+		row := ImportRow{
+			Label:  field(record, "label"),
+			Email:  field(record, "email"),
+			Status: field(record, "status"),
+			Tags:   splitList(field(record, "tags")),
+		}
+		for _, entry := range strings.Split(field(record, "tabs"), "\n") {
+			if entry = strings.TrimSpace(entry); entry == "" {
+				continue
+			}
+			tab := ImportTab{URL: entry}
+			if title, link, ok := strings.Cut(entry, "|"); ok {
+				tab = ImportTab{Title: strings.TrimSpace(title), URL: strings.TrimSpace(link)}
+			}
+			row.Tabs = append(row.Tabs, tab)
+		}
+		rows = append(rows, row)
+		if len(rows) > maxImportRows {
+			break
+		}
+	}
+	return checkImportSize(rows)
+}
+
+func checkImportSize(rows []ImportRow) ([]ImportRow, error) {
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: no accounts to import", ErrInvalidImport)
+	}
+	if len(rows) > maxImportRows {
+		return nil, fmt.Errorf("%w: at most %d accounts per import", ErrInvalidImport, maxImportRows)
+	}
+	return rows, nil
+}
+
+func splitList(value string) []string {
+	var out []string
+	for _, part := range strings.Split(value, ";") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// ImportAccounts validates every row and, unless dryRun is set, creates all
+// accounts in one transaction. If any row is invalid nothing is created and
+// the per-row errors are returned instead.
+func ImportAccounts(ctx context.Context, db *sql.DB, userID int64, rows []ImportRow, dryRun bool) (models.ImportResult, error) {
+	result := models.ImportResult{DryRun: dryRun, Total: len(rows), NewTags: []string{}, Rows: make([]models.ImportRowResult, len(rows))}
+
+	seenEmails := map[string]int{}
+	emails := make([]string, 0, len(rows))
+	tagNames := map[string]string{}
+	for i := range rows {
+		rows[i] = normalizeImportRow(rows[i])
+		res := &result.Rows[i]
+		res.Row, res.Label, res.Email = i+1, rows[i].Label, rows[i].Email
+		res.Errors = validateImportRow(rows[i])
+
+		key := strings.ToLower(rows[i].Email)
+		if key != "" {
+			if first, ok := seenEmails[key]; ok {
+				res.Errors = append(res.Errors, fmt.Sprintf("duplicate email, already used in row %d", first))
+			} else {
+				seenEmails[key] = i + 1
+				emails = append(emails, key)
+			}
+		}
+		for _, name := range rows[i].Tags {
+			tagNames[strings.ToLower(name)] = name
+		}
+	}
+
+	existing, err := existingAccountEmails(ctx, db, userID, emails)
+	if err != nil {
+		return result, err
+	}
+	for i := range rows {
+		if existing[strings.ToLower(rows[i].Email)] {
+			result.Rows[i].Errors = append(result.Rows[i].Errors, "an account with this email already exists")
+		}
+		if len(result.Rows[i].Errors) == 0 {
+			result.Valid++
+		}
+	}
+
+	knownTags, err := tagIDsByName(ctx, db, userID)
+	if err != nil {
+		return result, err
+	}
+	for key, name := range tagNames {
+		if _, ok := knownTags[key]; !ok {
+			result.NewTags = append(result.NewTags, name)
+		}
+	}
+	sort.Strings(result.NewTags)
+
+	if dryRun || result.Valid != result.Total {
+		return result, nil
+	}
+
+	err = withTx(ctx, db, func(tx *sql.Tx) error {
+		// The checks above ran outside the transaction, so an account or tag
+		// created since then is caught here. Locking the user serialises
+		// imports; a tag created elsewhere shows up as a unique violation.
+		if _, err := tx.ExecContext(ctx, `SELECT id FROM users WHERE id = $1 FOR UPDATE;`, userID); err != nil {
+			return fmt.Errorf("lock user: %w", err)
+		}
+		existing, err := existingAccountEmails(ctx, tx, userID, emails)
+		if err != nil {
+			return err
+		}
+		conflict := false
+		for i := range rows {
+			if existing[strings.ToLower(rows[i].Email)] {
+				result.Rows[i].Errors = append(result.Rows[i].Errors, "an account with this email already exists")
+				conflict = true
+			}
+		}
+		if conflict {
+			return errImportConflict
+		}
+
+		if knownTags, err = tagIDsByName(ctx, tx, userID); err != nil {
+			return err
+		}
+		for _, name := range result.NewTags {
+			if _, ok := knownTags[strings.ToLower(name)]; ok {
+				continue
+			}
+			var id int64
+			if err := tx.QueryRowContext(
+				ctx,
+				`INSERT INTO tags (user_id, name, color, created_at) VALUES ($1, $2, $3, $4) RETURNING id;`,
+				userID,
+				name,
+				defaultTagColor,
+				time.Now().UTC().Format(time.RFC3339Nano),
+			).Scan(&id); err != nil {
+				if isUniqueViolation(err) {
+					markImportTagConflict(&result, rows, name)
+					return errImportConflict
+				}
+				return fmt.Errorf("create tag %q: %w", name, err)
+			}
+			knownTags[strings.ToLower(name)] = id
+		}
+
+		for i, row := range rows {
+			account, err := insertAccountTx(ctx, tx, models.Account{UserID: userID, Label: row.Label, NetflixEmail: row.Email, Status: row.Status})
+			if err != nil {
+				return fmt.Errorf("row %d: %w", i+1, err)
+			}
+			if err := InsertDefaultTabs(ctx, tx, userID, account.ID, nil); err != nil {
+				return fmt.Errorf("row %d: %w", i+1, err)
+			}
+			for _, name := range row.Tags {
+				if _, err := tx.ExecContext(
+					ctx,
+					`INSERT INTO account_tags (account_id, tag_id) VALUES ($1, $2) ON CONFLICT DO NOTHING;`,
+					account.ID,
+					knownTags[strings.ToLower(name)],
+				); err != nil {
+					return fmt.Errorf("row %d: tag account: %w", i+1, err)
+				}
+			}
+			extra := make([]models.Tab, len(row.Tabs))
+			for j, tab := range row.Tabs {
+				extra[j] = models.Tab{Title: tab.Title, URL: tab.URL}
+			}
+			if err := appendTabsTx(ctx, tx, account.ID, extra); err != nil {
+				return fmt.Errorf("row %d: %w", i+1, err)
+			}
+			id := account.ID
+			result.Rows[i].AccountID = &id
+		}
+
+		return recordAudit(ctx, tx, "account.import", "user", userID, nil, map[string]any{"created": len(rows), "new_tags": result.NewTags})
+	})
+	if err != nil {
+		for i := range result.Rows {
+			result.Rows[i].AccountID = nil
+		}
+		if errors.Is(err, errImportConflict) {
+			result.Valid = 0
+			for _, row := range result.Rows {
+				if len(row.Errors) == 0 {
+					result.Valid++
+				}
+			}
+			return result, nil
+		}
+		return result, err
+	}
+	result.Created = len(rows)
+	return result, nil
+}
+
+// markImportTagConflict reports the rows using a tag that another request
+// created while the import ran.
+func markImportTagConflict(result *models.ImportResult, rows []ImportRow, tag string) {
+	for i, row := range rows {
+		for _, name := range row.Tags {
+			if strings.EqualFold(name, tag) {
+				result.Rows[i].Errors = append(result.Rows[i].Errors, fmt.Sprintf("tag %q was created by another request during the import; import again", name))
+				break
+			}
+		}
+	}
+}
+
+func normalizeImportRow(row ImportRow) ImportRow {
+	row.Label = strings.TrimSpace(row.Label)
+	row.Email = strings.TrimSpace(row.Email)
+	if strings.TrimSpace(row.Status) == "" {
+		row.Status = StatusPendingLogin
+	} else if status := normalizeStatus(row.Status); status != "" {
+		row.Status = status
+	}
+
+	seen := map[string]bool{}
+	tags := row.Tags[:0]
+	for _, name := range row.Tags {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		tags = append(tags, name)
+	}
+	row.Tags = tags
+
+	for i := range row.Tabs {
+		// Invalid URLs are kept as given and reported by validateImportRow.
+		if title, link, err := normalizeTabInput(row.Tabs[i].Title, row.Tabs[i].URL); err == nil {
+			row.Tabs[i].Title, row.Tabs[i].URL = title, link
+		}
+	}
+	return row
+}
+
+func validateImportRow(row ImportRow) []string {
+	var errs []string
+	if row.Label == "" {
+		errs = append(errs, "label is required")
+	}
+	if row.Email == "" {
+		errs = append(errs, "email is required")
+	} else if addr, err := mail.ParseAddress(row.Email); err != nil || addr.Address != row.Email {
+		errs = append(errs, "email is not a valid address")
+	}
+	if normalizeStatus(row.Status) == "" {
+		errs = append(errs, fmt.Sprintf("unknown status %q", row.Status))
+	} else if err := checkInitialStatus(row.Status); err != nil {
+		errs = append(errs, err.Error())
+	}
+	for _, name := range row.Tags {
+		if _, _, err := normalizeTag(name, ""); err != nil {
+			errs = append(errs, fmt.Sprintf("tag %q: %v", name, err))
+		}
+	}
+	for i, tab := range row.Tabs {
+		link, err := normalizeTabURLTemplate(tab.URL)
+		if err == nil {
+			err = checkNewAccountTabURL(link)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("tab %d: %v", i+1, err))
+		}
+	}
+	return errs
+}
+
+// existingAccountEmails returns which of the lower-cased emails already
+// belong to one of the user's accounts outside the trash.
+func existingAccountEmails(ctx context.Context, db querier, userID int64, emails []string) (map[string]bool, error) {
+	found := map[string]bool{}
+	if len(emails) == 0 {
+		return found, nil
+	}
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT LOWER(netflix_email) FROM accounts WHERE user_id = $1 AND deleted_at IS NULL AND LOWER(netflix_email) = ANY($2);`,
+		userID,
+		pq.Array(emails),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("check existing emails: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, fmt.Errorf("scan email: %w", err)
+		}
+		found[email] = true
+	}
+	return found, rows.Err()
+}
+
+// tagIDsByName maps the lower-cased names of the user's tags to their ids.
+func tagIDsByName(ctx context.Context, db querier, userID int64) (map[string]int64, error) {
+	rows, err := db.QueryContext(ctx, `SELECT id, LOWER(name) FROM tags WHERE user_id = $1;`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("query tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := map[string]int64{}
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, fmt.Errorf("scan tag: %w", err)
+		}
+		tags[name] = id
+	}
+	return tags, rows.Err()
+}
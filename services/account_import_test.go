@@ -0,0 +1,30 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseImportCSVKeepsSemicolonsInTabURLs(t *testing.T) {
+	input := "label,email,tags,tabs\n" +
+		"Main,main@example.com,family; kids,\"Search|https://www.netflix.com/search?q=a;b\n" +
+		"https://mail.google.com/mail/u/0/;x\r\n\"\n"
+	rows, err := ParseImportCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseImportCSV: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+	if want := []string{"family", "kids"}; !reflect.DeepEqual(rows[0].Tags, want) {
+		t.Errorf("tags = %q, want %q", rows[0].Tags, want)
+	}
+	want := []ImportTab{
+		{Title: "Search", URL: "https://www.netflix.com/search?q=a;b"},
+		{URL: "https://mail.google.com/mail/u/0/;x"},
+	}
+	if !reflect.DeepEqual(rows[0].Tabs, want) {
+		t.Errorf("tabs = %+v, want %+v", rows[0].Tabs, want)
+	}
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"netflix_central/models"
+)
+
+var ErrInvalidQuery = errors.New("invalid query")
+
+const maxAccountPageSize = 200
+
+// accountSortColumns maps public sort keys to SQL expressions. Each
+// expression must be non-null so it can be used in a keyset comparison.
+var accountSortColumns = map[string]string{
+	"label":       "LOWER(label)",
+	"email":       "LOWER(netflix_email)",
+	"created":     "created_at",
+	"last_opened": "COALESCE(last_opened_at, 'epoch'::timestamptz)",
+	// stale is last_opened with the least recently opened accounts first.
+	"stale": "COALESCE(last_opened_at, 'epoch'::timestamptz)",
+}
+
+// AccountQuery holds the search, filter, sort and paging options for listing accounts.
+type AccountQuery struct {
+	Search string
+	Status string
+	Tags   []string
+	Folder string
+	// StaleDays keeps accounts not opened in that many days, including
+	// accounts never opened.
+	StaleDays int
+	Sort      string
+	Order     string
+	Cursor    string
+	Limit     int
+}
+
+type accountCursor struct {
+	Value string `json:"v"`
+	ID    int64  `json:"id"`
+}
+
+// ListAccounts returns accounts owned by the authenticated user matching the query.
+// A zero Limit returns every match; otherwise NextCursor is set when more rows remain.
+func ListAccounts(ctx context.Context, db *sql.DB, userID int64, q AccountQuery) (models.AccountPage, error) {
+	page := models.AccountPage{Accounts: []models.Account{}}
+
+	sortKey := strings.ToLower(strings.TrimSpace(q.Sort))
+	if sortKey == "" {
+		sortKey = "created"
+	}
+	sortExpr, ok := accountSortColumns[sortKey]
+	if !ok {
+		return page, fmt.Errorf("%w: unknown sort %q", ErrInvalidQuery, q.Sort)
+	}
+
+	order := strings.ToLower(strings.TrimSpace(q.Order))
+	switch order {
+	case "":
+		order = "asc"
+		if sortKey == "created" || sortKey == "last_opened" {
+			order = "desc"
+		}
+	case "asc", "desc":
+	default:
+		return page, fmt.Errorf("%w: order must be asc or desc", ErrInvalidQuery)
+	}
+	if q.StaleDays < 0 {
+		return page, fmt.Errorf("%w: stale_days must not be negative", ErrInvalidQuery)
+	}
+
+	if q.Limit < 0 || q.Limit > maxAccountPageSize {
+		return page, fmt.Errorf("%w: limit must be between 0 and %d, 0 for all", ErrInvalidQuery, maxAccountPageSize)
+	}
+
+	conds := []string{"user_id = $1", "deleted_at IS NULL"}
+	args := []any{userID}
+	addArg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if search := strings.TrimSpace(q.Search); search != "" {
+		pattern := "%" + escapeLike(search) + "%"
+		p := addArg(pattern)
+		conds = append(conds, fmt.Sprintf("(label ILIKE %s OR netflix_email ILIKE %s)", p, p))
+	}
+
+	if q.Status != "" {
+		status := normalizeStatus(q.Status)
+		if status == "" {
+			return page, fmt.Errorf("%w: unknown status %q", ErrInvalidQuery, q.Status)
+		}
+		conds = append(conds, "status = "+addArg(status))
+	}
+
+	// Every requested tag must be present; a tag may be given by id or name.
+	for _, tag := range q.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		p := addArg(tag)
+		conds = append(conds, fmt.Sprintf(
+			`EXISTS (SELECT 1 FROM account_tags at JOIN tags t ON t.id = at.tag_id WHERE at.account_id = accounts.id AND (t.id::text = %s OR LOWER(t.name) = LOWER(%s)))`,
+			p,
+			p,
+		))
+	}
+
+	if q.StaleDays > 0 {
+		conds = append(conds, fmt.Sprintf("(last_opened_at IS NULL OR last_opened_at < NOW() - make_interval(days => %s))", addArg(q.StaleDays)))
+	}
+
+	// A folder filter includes every nested subfolder; "none" selects unfiled accounts.
+	switch folder := strings.TrimSpace(q.Folder); folder {
+	case "":
+	case "none":
+		conds = append(conds, "folder_id IS NULL")
+	default:
+		folderID, err := strconv.ParseInt(folder, 10, 64)
+		if err != nil {
+			return page, fmt.Errorf("%w: invalid folder %q", ErrInvalidQuery, q.Folder)
+		}
+		conds = append(conds, fmt.Sprintf(
+			`folder_id IN (WITH RECURSIVE subtree AS (
+				SELECT id, 1 AS depth FROM folders WHERE id = %s AND user_id = $1
+				UNION ALL
+				SELECT f.id, s.depth + 1 FROM folders f JOIN subtree s ON f.parent_id = s.id
+				WHERE s.depth < %s
+			) SELECT id FROM subtree)`,
+			addArg(folderID),
+			addArg(maxFolderDepth),
+		))
+	}
+
+	if q.Cursor != "" {
+		cur, err := decodeAccountCursor(q.Cursor)
+		if err != nil {
+			return page, err
+		}
+		cmp := ">"
+		if order == "desc" {
+			cmp = "<"
+		}
+		conds = append(conds, fmt.Sprintf("(%s, id) %s (%s, %s)", sortExpr, cmp, addArg(cur.Value), addArg(cur.ID)))
+	}
+
+	query := fmt.Sprintf(
+		`SELECT %s, %s::text FROM accounts WHERE %s ORDER BY %s %s, id %s`,
+		accountColumns,
+		sortExpr,
+		strings.Join(conds, " AND "),
+		sortExpr,
+		order,
+		order,
+	)
+	if q.Limit > 0 {
+		// Fetch one extra row to know whether another page exists.
+		query += " LIMIT " + addArg(q.Limit+1)
+	}
+
+	rows, err := db.QueryContext(ctx, query+";", args...)
+	if err != nil {
+		return page, fmt.Errorf("query accounts: %w", err)
+	}
+	defer rows.Close()
+
+	var sortValues []string
+	for rows.Next() {
+		var sortValue string
+		acc, err := scanAccount(rows, &sortValue)
+		if err != nil {
+			return page, fmt.Errorf("scan account: %w", err)
+		}
+		page.Accounts = append(page.Accounts, acc)
+		sortValues = append(sortValues, sortValue)
+	}
+	if err := rows.Err(); err != nil {
+		return page, err
+	}
+
+	if q.Limit > 0 && len(page.Accounts) > q.Limit {
+		page.Accounts = page.Accounts[:q.Limit]
+		last := page.Accounts[q.Limit-1]
+		page.NextCursor = encodeAccountCursor(accountCursor{Value: sortValues[q.Limit-1], ID: last.ID})
+	}
+
+	if err := loadAccountTags(ctx, db, page.Accounts); err != nil {
+		return page, err
+	}
+	if err := loadAccountSubscriptions(ctx, db, page.Accounts); err != nil {
+		return page, err
+	}
+
+	return page, nil
+}
+
+func encodeAccountCursor(cur accountCursor) string {
+	raw, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeAccountCursor(value string) (accountCursor, error) {
+	var cur accountCursor
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cur, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	if err := json.Unmarshal(raw, &cur); err != nil || cur.ID <= 0 {
+		return cur, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	return cur, nil
+}
+
+// escapeLike escapes LIKE wildcards so user input matches literally.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
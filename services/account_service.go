@@ -10,46 +10,90 @@ import (
 	"netflix_central/models"
 )
 
-// ListAccounts returns accounts owned by the authenticated user.
-func ListAccounts(ctx context.Context, db *sql.DB, userID int64) ([]models.Account, error) {
-	rows, err := db.QueryContext(ctx, `SELECT id, user_id, label, netflix_email, status, chrome_profile, created_at FROM accounts WHERE user_id = $1 ORDER BY created_at DESC;`, userID)
-	if err != nil {
-		return nil, fmt.Errorf("query accounts: %w", err)
-	}
-	defer rows.Close()
-
-	var accounts []models.Account
-	for rows.Next() {
-		var acc models.Account
-		var created string
-		if err := rows.Scan(&acc.ID, &acc.UserID, &acc.Label, &acc.NetflixEmail, &acc.Status, &acc.ChromeProfile, &created); err != nil {
-			return nil, fmt.Errorf("scan account: %w", err)
-		}
-		acc.CreatedAt = parseSQLiteTime(created)
-		accounts = append(accounts, acc)
-	}
+const accountColumns = `id, user_id, label, netflix_email, status, chrome_profile, created_at, last_opened_at, folder_id, vault_enabled, deleted_at, version`
 
-	return accounts, rows.Err()
+type rowScanner interface {
+	Scan(dest ...any) error
 }
 
-// GetAccount ensures the account belongs to the given user.
-func GetAccount(ctx context.Context, db *sql.DB, id, userID int64) (models.Account, error) {
+// scanAccount reads the accountColumns of a row; extra receives any trailing columns.
+func scanAccount(row rowScanner, extra ...any) (models.Account, error) {
 	var acc models.Account
 	var created string
-	if err := db.QueryRowContext(
+	var lastOpened sql.NullString
+	var folderID sql.NullInt64
+	var deletedAt sql.NullString
+	dest := append([]any{&acc.ID, &acc.UserID, &acc.Label, &acc.NetflixEmail, &acc.Status, &acc.ChromeProfile, &created, &lastOpened, &folderID, &acc.VaultEnabled, &deletedAt, &acc.Version}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return acc, err
+	}
+	acc.CreatedAt = parseSQLiteTime(created)
+	if folderID.Valid {
+		acc.FolderID = &folderID.Int64
+	}
+	if lastOpened.Valid {
+		t := parseSQLiteTime(lastOpened.String)
+		acc.LastOpenedAt = &t
+	}
+	if deletedAt.Valid {
+		t := parseSQLiteTime(deletedAt.String)
+		acc.DeletedAt = &t
+	}
+	return acc, nil
+}
+
+// GetAccount ensures the account belongs to the given user.
+func GetAccount(ctx context.Context, db querier, id, userID int64) (models.Account, error) {
+	acc, err := scanAccount(db.QueryRowContext(
 		ctx,
-		`SELECT id, user_id, label, netflix_email, status, chrome_profile, created_at FROM accounts WHERE id = $1 AND user_id = $2;`,
+		`SELECT `+accountColumns+` FROM accounts WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;`,
 		id,
 		userID,
-	).Scan(&acc.ID, &acc.UserID, &acc.Label, &acc.NetflixEmail, &acc.Status, &acc.ChromeProfile, &created); err != nil {
+	))
+	if err != nil {
 		return acc, err
 	}
-	acc.CreatedAt = parseSQLiteTime(created)
-	return acc, nil
+
+	accounts := []models.Account{acc}
+	if err := loadAccountTags(ctx, db, accounts); err != nil {
+		return acc, err
+	}
+	if err := loadAccountSubscriptions(ctx, db, accounts); err != nil {
+		return acc, err
+	}
+	return accounts[0], nil
+}
+
+// MarkAccountOpened records that a session was just launched for the
+// account, with the given outcome, and returns the launch id.
+func MarkAccountOpened(ctx context.Context, db *sql.DB, id, userID int64, outcome string) (int64, error) {
+	openedAt := time.Now().UTC().Format(time.RFC3339Nano)
+	var launchID int64
+	err := withTx(ctx, db, func(tx *sql.Tx) error {
+		if err := lockOwnedAccount(ctx, tx, id, userID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE accounts SET last_opened_at = $1 WHERE id = $2;`, openedAt, id); err != nil {
+			return fmt.Errorf("mark account opened: %w", err)
+		}
+		if err := tx.QueryRowContext(
+			ctx,
+			`INSERT INTO launches (account_id, user_id, started_at, outcome) VALUES ($1, $2, $3, $4) RETURNING id;`,
+			id,
+			userID,
+			openedAt,
+			outcome,
+		).Scan(&launchID); err != nil {
+			return fmt.Errorf("insert launch: %w", err)
+		}
+		return recordAudit(ctx, tx, "session.open", "account", id, nil, map[string]any{"opened_at": openedAt, "launch_id": launchID})
+	})
+	return launchID, err
 }
 
-// CreateAccount inserts a new account tied to the user.
-func CreateAccount(ctx context.Context, db *sql.DB, userID int64, label, email, status string) (models.Account, error) {
+// CreateAccount inserts a new account tied to the user. Its tabs come from
+// templateID, or from the default template when templateID is nil.
+func CreateAccount(ctx context.Context, db *sql.DB, userID int64, label, email, status string, templateID *int64) (models.Account, error) {
 	label = strings.TrimSpace(label)
 	email = strings.TrimSpace(email)
 	status = normalizeStatus(status)
@@ -58,104 +102,172 @@ func CreateAccount(ctx context.Context, db *sql.DB, userID int64, label, email,
 		return models.Account{}, fmt.Errorf("label and email are required")
 	}
 	if status == "" {
-		return models.Account{}, fmt.Errorf("status is required")
+		return models.Account{}, fmt.Errorf("%w: status is required", ErrInvalidStatus)
+	}
+	if err := checkInitialStatus(status); err != nil {
+		return models.Account{}, err
 	}
 
-	tx, err := db.BeginTx(ctx, nil)
+	account := models.Account{UserID: userID, Label: label, NetflixEmail: email, Status: status}
+	err := withTx(ctx, db, func(tx *sql.Tx) error {
+		var err error
+		if account, err = insertAccountTx(ctx, tx, account); err != nil {
+			return err
+		}
+		return InsertDefaultTabs(ctx, tx, userID, account.ID, templateID)
+	})
 	if err != nil {
 		return models.Account{}, err
 	}
+	return account, nil
+}
 
-	profileName := generateProfileName(label, email)
-	createdAt := time.Now().UTC().Format(time.RFC3339Nano)
+// insertAccountTx creates an already validated account and its first status
+// history entry. A Chrome profile name and creation time are filled in when
+// missing; tabs are left to the caller.
+func insertAccountTx(ctx context.Context, tx *sql.Tx, account models.Account) (models.Account, error) {
+	if account.ChromeProfile == "" {
+		account.ChromeProfile = generateProfileName(account.Label, account.NetflixEmail)
+	}
+	if account.CreatedAt.IsZero() {
+		account.CreatedAt = time.Now().UTC()
+	}
+	if account.Tags == nil {
+		account.Tags = []models.Tag{}
+	}
+	account.Version = 1
 
-	result, err := tx.ExecContext(
+	if err := tx.QueryRowContext(
 		ctx,
-		`INSERT INTO accounts (user_id, label, netflix_email, status, chrome_profile, created_at) VALUES ($1, $2, $3, $4, $5, $6);`,
-		userID,
-		label,
-		email,
-		status,
-		profileName,
-		createdAt,
-	)
-	if err != nil {
-		tx.Rollback()
+		`INSERT INTO accounts (user_id, label, netflix_email, status, chrome_profile, created_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id;`,
+		account.UserID,
+		account.Label,
+		account.NetflixEmail,
+		account.Status,
+		account.ChromeProfile,
+		account.CreatedAt.Format(time.RFC3339Nano),
+	).Scan(&account.ID); err != nil {
 		return models.Account{}, fmt.Errorf("insert account: %w", err)
 	}
 
-	accountID, err := result.LastInsertId()
-	if err != nil {
-		tx.Rollback()
-		return models.Account{}, fmt.Errorf("get account id: %w", err)
-	}
-
-	if err := InsertDefaultTabs(ctx, tx, accountID); err != nil {
-		tx.Rollback()
+	if err := recordStatusHistory(ctx, tx, account.ID, account.UserID, "", account.Status, "created"); err != nil {
 		return models.Account{}, err
 	}
-
-	if err := tx.Commit(); err != nil {
-		return models.Account{}, fmt.Errorf("commit account: %w", err)
+	if err := recordAudit(ctx, tx, "account.create", "account", account.ID, nil, account); err != nil {
+		return models.Account{}, err
 	}
-
-	return models.Account{
-		ID:            accountID,
-		UserID:        userID,
-		Label:         label,
-		NetflixEmail:  email,
-		Status:        status,
-		ChromeProfile: profileName,
-		CreatedAt:     parseSQLiteTime(createdAt),
-	}, nil
+	return account, nil
 }
 
-// UpdateAccount edits an account owned by the user.
-func UpdateAccount(ctx context.Context, db *sql.DB, id, userID int64, label, email, status string) (models.Account, error) {
+// UpdateAccount edits an account owned by the user. A status change goes
+// through the same transition rules as ChangeAccountStatus. A non-zero
+// ifMatch must equal the account's current version, which goes up by one.
+func UpdateAccount(ctx context.Context, db *sql.DB, id, userID int64, label, email, status string, ifMatch int) (models.Account, error) {
 	label = strings.TrimSpace(label)
 	email = strings.TrimSpace(email)
 	status = normalizeStatus(status)
 
 	if status == "" {
-		return models.Account{}, fmt.Errorf("status is required")
+		return models.Account{}, fmt.Errorf("%w: status is required", ErrInvalidStatus)
 	}
 
-	result, err := db.ExecContext(
-		ctx,
-		`UPDATE accounts SET label = $1, netflix_email = $2, status = $3 WHERE id = $4 AND user_id = $5;`,
-		label,
-		email,
-		status,
-		id,
-		userID,
-	)
-	if err != nil {
-		return models.Account{}, fmt.Errorf("update account: %w", err)
-	}
+	err := withTx(ctx, db, func(tx *sql.Tx) error {
+		if err := lockAccountVersion(ctx, tx, id, userID, ifMatch); err != nil {
+			return err
+		}
+		before, err := GetAccount(ctx, tx, id, userID)
+		if err != nil {
+			return err
+		}
+		if _, err := transitionStatusTx(ctx, tx, id, userID, status, ""); err != nil {
+			return err
+		}
 
-	if rows, _ := result.RowsAffected(); rows == 0 {
-		return models.Account{}, sql.ErrNoRows
+		// Set rather than increment: a status change already bumped it.
+		if _, err := tx.ExecContext(
+			ctx,
+			`UPDATE accounts SET label = $1, netflix_email = $2, version = $3 WHERE id = $4;`,
+			label,
+			email,
+			before.Version+1,
+			id,
+		); err != nil {
+			return fmt.Errorf("update account: %w", err)
+		}
+
+		after := before
+		after.Label, after.NetflixEmail, after.Status, after.Version = label, email, status, before.Version+1
+		return recordAudit(ctx, tx, "account.update", "account", id, before, after)
+	})
+	if err != nil {
+		return models.Account{}, err
 	}
 
 	return GetAccount(ctx, db, id, userID)
 }
 
-// DeleteAccount removes an account owned by the user.
+// DeleteAccount moves an account owned by the user to the trash. It can be
+// restored until the purge job removes it for good. Ownership is checked
+// before anything is written, so another user's account is never touched.
 func DeleteAccount(ctx context.Context, db *sql.DB, id, userID int64) error {
-	_, err := db.ExecContext(ctx, `DELETE FROM tabs WHERE account_id = $1;`, id)
-	if err != nil {
-		return fmt.Errorf("delete tabs for account: %w", err)
+	return withTx(ctx, db, func(tx *sql.Tx) error {
+		return deleteAccountTx(ctx, tx, id, userID)
+	})
+}
+
+func deleteAccountTx(ctx context.Context, tx *sql.Tx, id, userID int64) error {
+	if err := lockOwnedAccount(ctx, tx, id, userID); err != nil {
+		return err
 	}
+	deletedAt := time.Now().UTC().Format(time.RFC3339Nano)
+	if _, err := tx.ExecContext(ctx, `UPDATE accounts SET deleted_at = $1 WHERE id = $2;`, deletedAt, id); err != nil {
+		return fmt.Errorf("delete account: %w", err)
+	}
+	return recordAudit(ctx, tx, "account.delete", "account", id, map[string]any{"deleted_at": nil}, map[string]any{"deleted_at": deletedAt})
+}
 
-	result, err := db.ExecContext(ctx, `DELETE FROM accounts WHERE id = $1 AND user_id = $2;`, id, userID)
+// OpenAccount launches Chrome with the account's profile and tabs and
+// records the launch, including a failed one. With tabIDs only those tabs are opened; otherwise the
+// tabs' enabled and open_on flags decide, see launchTabs. The returned
+// warnings name tab options that were not applied, see LaunchChrome.
+func OpenAccount(ctx context.Context, db *sql.DB, id, userID int64, tabIDs []int64) ([]string, error) {
+	account, err := GetAccount(ctx, db, id, userID)
 	if err != nil {
-		return fmt.Errorf("delete account: %w", err)
+		return nil, err
+	}
+	tabs, err := GetTabs(ctx, db, id)
+	if err != nil {
+		return nil, err
+	}
+	if tabs, err = launchTabs(account, tabs, tabIDs); err != nil {
+		return nil, err
+	}
+	groups, err := ListTabGroups(ctx, db, id)
+	if err != nil {
+		return nil, err
+	}
+	vars, err := GetAccountVariables(ctx, db, id)
+	if err != nil {
+		return nil, err
+	}
+	done, warnings, err := LaunchChrome(account, tabs, groups, vars)
+	if err != nil {
+		recordFailedLaunch(ctx, db, id, userID, err)
+		return nil, err
 	}
 
-	if rows, _ := result.RowsAffected(); rows == 0 {
-		return sql.ErrNoRows
+	outcome := models.LaunchRunning
+	if done == nil {
+		outcome = models.LaunchReused
+	}
+	launchID, err := MarkAccountOpened(ctx, db, id, userID, outcome)
+	if err != nil {
+		return nil, err
+	}
+	if done != nil {
+		go finishLaunch(db, launchID, done)
 	}
-	return nil
+	return warnings, nil
 }
 
 func generateProfileName(label, email string) string {
@@ -191,7 +303,7 @@ func parseSQLiteTime(value string) time.Time {
 
 func normalizeStatus(value string) string {
 	s := strings.ToLower(strings.TrimSpace(value))
-	if s == "active" || s == "inactive" {
+	if _, ok := defaultStatusTransitions[s]; ok {
 		return s
 	}
 	return ""
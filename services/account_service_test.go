@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDeleteAccountRejectsOtherUser(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	owner, other := createTestUser(t, db), createTestUser(t, db)
+	acc := createTestAccount(t, db, owner)
+
+	if err := DeleteAccount(ctx, db, acc.ID, other); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("DeleteAccount by another user = %v, want %v", err, sql.ErrNoRows)
+	}
+	if _, err := GetAccount(ctx, db, acc.ID, owner); err != nil {
+		t.Fatalf("account is gone after another user's delete: %v", err)
+	}
+}
+
+func TestTrashAndRestoreRejectOtherUser(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	owner, other := createTestUser(t, db), createTestUser(t, db)
+	acc := createTestAccount(t, db, owner)
+
+	if err := DeleteAccount(ctx, db, acc.ID, owner); err != nil {
+		t.Fatalf("DeleteAccount by owner: %v", err)
+	}
+	if err := DeleteAccount(ctx, db, acc.ID, owner); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("deleting a trashed account = %v, want %v", err, sql.ErrNoRows)
+	}
+
+	trashed, err := ListTrashedAccounts(ctx, db, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range trashed {
+		if a.ID == acc.ID {
+			t.Fatal("another user's trash lists the account")
+		}
+	}
+	if _, err := RestoreAccount(ctx, db, acc.ID, other); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("RestoreAccount by another user = %v, want %v", err, sql.ErrNoRows)
+	}
+
+	restored, err := RestoreAccount(ctx, db, acc.ID, owner)
+	if err != nil {
+		t.Fatalf("RestoreAccount by owner: %v", err)
+	}
+	if restored.DeletedAt != nil {
+		t.Fatal("restored account is still marked deleted")
+	}
+}
+
+func TestTabMutationsRejectOtherUser(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	owner, other := createTestUser(t, db), createTestUser(t, db)
+	acc := createTestAccount(t, db, owner)
+
+	tab, err := CreateTab(ctx, db, acc.ID, owner, "Browse", "https://www.netflix.com/browse", TabOptions{})
+	if err != nil {
+		t.Fatalf("CreateTab by owner: %v", err)
+	}
+	before, err := GetTabs(ctx, db, acc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]int64, len(before))
+	for i, tb := range before {
+		ids[len(before)-1-i] = tb.ID
+	}
+
+	mutations := map[string]func() error{
+		"CreateTab": func() error {
+			_, err := CreateTab(ctx, db, acc.ID, other, "Intruder", "https://www.netflix.com/", TabOptions{})
+			return err
+		},
+		"UpdateTab": func() error {
+			_, err := UpdateTab(ctx, db, tab.ID, acc.ID, other, "Intruder", "https://www.netflix.com/", TabOptions{}, 0)
+			return err
+		},
+		"DeleteTab": func() error {
+			return DeleteTab(ctx, db, tab.ID, acc.ID, other)
+		},
+		"ReorderTabs": func() error {
+			return ReorderTabs(ctx, db, acc.ID, other, ids)
+		},
+		"MoveTab": func() error {
+			_, err := MoveTab(ctx, db, tab.ID, acc.ID, other, 1)
+			return err
+		},
+	}
+	for name, mutate := range mutations {
+		if err := mutate(); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("%s by another user = %v, want %v", name, err, sql.ErrNoRows)
+		}
+	}
+
+	after, err := GetTabs(ctx, db, acc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(before, after) {
+		t.Fatalf("tabs changed by another user:\nbefore %+v\nafter  %+v", before, after)
+	}
+}
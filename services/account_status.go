@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"netflix_central/models"
+)
+
+var (
+	ErrInvalidStatus           = errors.New("invalid status")
+	ErrIllegalStatusTransition = errors.New("illegal status transition")
+)
+
+const (
+	StatusPendingLogin  = "pending_login"
+	StatusActive        = "active"
+	StatusInactive      = "inactive"
+	StatusPaymentFailed = "payment_failed"
+	StatusOnHold        = "on_hold"
+	StatusExpired       = "expired"
+	StatusCancelled     = "cancelled"
+)
+
+// defaultStatusTransitions lists, for every status, the statuses it may move to.
+var defaultStatusTransitions = map[string][]string{
+	StatusPendingLogin:  {StatusActive, StatusInactive, StatusCancelled},
+	StatusActive:        {StatusInactive, StatusPaymentFailed, StatusOnHold, StatusExpired, StatusCancelled},
+	StatusInactive:      {StatusActive, StatusPendingLogin, StatusCancelled},
+	StatusPaymentFailed: {StatusActive, StatusOnHold, StatusExpired, StatusCancelled},
+	StatusOnHold:        {StatusActive, StatusPaymentFailed, StatusExpired, StatusCancelled},
+	StatusExpired:       {StatusPendingLogin, StatusActive, StatusCancelled},
+	StatusCancelled:     {StatusPendingLogin},
+}
+
+// initialStatuses are the statuses a new account may start in.
+var initialStatuses = []string{StatusPendingLogin, StatusActive, StatusInactive}
+
+var (
+	statusRulesOnce sync.Once
+	statusRules     map[string][]string
+)
+
+// statusTransitions returns the transition table. ACCOUNT_STATUS_RULES may
+// point to a JSON file with the same shape as defaultStatusTransitions.
+func statusTransitions() map[string][]string {
+	statusRulesOnce.Do(func() {
+		statusRules = defaultStatusTransitions
+		path := strings.TrimSpace(os.Getenv("ACCOUNT_STATUS_RULES"))
+		if path == "" {
+			return
+		}
+		rules, err := loadStatusRules(path)
+		if err != nil {
+			log.Printf("account status rules: %v; using defaults", err)
+			return
+		}
+		statusRules = rules
+	})
+	return statusRules
+}
+
+func loadStatusRules(path string) (map[string][]string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules map[string][]string
+	if err := json.Unmarshal(raw, &rules); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for from, targets := range rules {
+		if _, ok := defaultStatusTransitions[from]; !ok {
+			return nil, fmt.Errorf("unknown status %q", from)
+		}
+		for _, to := range targets {
+			if _, ok := defaultStatusTransitions[to]; !ok {
+				return nil, fmt.Errorf("unknown status %q", to)
+			}
+		}
+	}
+	return rules, nil
+}
+
+// AllowedStatusTransitions returns the statuses reachable from the given one.
+func AllowedStatusTransitions(from string) []string {
+	targets := append([]string(nil), statusTransitions()[from]...)
+	sort.Strings(targets)
+	return targets
+}
+
+func checkStatusTransition(from, to string) error {
+	if from == to {
+		return nil
+	}
+	for _, allowed := range statusTransitions()[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+	allowed := AllowedStatusTransitions(from)
+	if len(allowed) == 0 {
+		return fmt.Errorf("%w: %s is a final status", ErrIllegalStatusTransition, from)
+	}
+	return fmt.Errorf("%w: cannot change status from %s to %s (allowed: %s)", ErrIllegalStatusTransition, from, to, strings.Join(allowed, ", "))
+}
+
+func checkInitialStatus(status string) error {
+	for _, s := range initialStatuses {
+		if s == status {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: a new account must start as one of %s", ErrInvalidStatus, strings.Join(initialStatuses, ", "))
+}
+
+// ChangeAccountStatus moves an account to a new status, enforcing the
+// transition rules and recording the change in status_history. A non-zero
+// ifMatch must equal the account's current version.
+func ChangeAccountStatus(ctx context.Context, db *sql.DB, id, userID int64, status, note string, ifMatch int) (models.Account, error) {
+	to := normalizeStatus(status)
+	if to == "" {
+		return models.Account{}, fmt.Errorf("%w: %q", ErrInvalidStatus, status)
+	}
+
+	err := withTx(ctx, db, func(tx *sql.Tx) error {
+		if err := lockAccountVersion(ctx, tx, id, userID, ifMatch); err != nil {
+			return err
+		}
+		from, err := transitionStatusTx(ctx, tx, id, userID, to, note)
+		if err != nil || from == to {
+			return err
+		}
+		return recordAudit(ctx, tx, "account.status", "account", id, map[string]any{"status": from}, map[string]any{"status": to, "note": note})
+	})
+	if err != nil {
+		return models.Account{}, err
+	}
+
+	return GetAccount(ctx, db, id, userID)
+}
+
+// transitionStatusTx locks the account row, validates the move and writes the
+// new status plus a history row. It returns the previous status; moving to the
+// current status is a no-op.
+func transitionStatusTx(ctx context.Context, tx *sql.Tx, id, userID int64, to, note string) (string, error) {
+	var from string
+	if err := tx.QueryRowContext(
+		ctx,
+		`SELECT status FROM accounts WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL FOR UPDATE;`,
+		id,
+		userID,
+	).Scan(&from); err != nil {
+		return "", err
+	}
+
+	if from == to {
+		return from, nil
+	}
+	if err := checkStatusTransition(from, to); err != nil {
+		return from, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE accounts SET status = $1, version = version + 1 WHERE id = $2 AND user_id = $3;`, to, id, userID); err != nil {
+		return from, fmt.Errorf("update status: %w", err)
+	}
+	return from, recordStatusHistory(ctx, tx, id, userID, from, to, note)
+}
+
+func recordStatusHistory(ctx context.Context, tx *sql.Tx, accountID, userID int64, from, to, note string) error {
+	if _, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO status_history (account_id, changed_by, from_status, to_status, note, changed_at) VALUES ($1, $2, $3, $4, $5, $6);`,
+		accountID,
+		userID,
+		from,
+		to,
+		strings.TrimSpace(note),
+		time.Now().UTC().Format(time.RFC3339Nano),
+	); err != nil {
+		return fmt.Errorf("insert status history: %w", err)
+	}
+	return nil
+}
+
+// GetStatusHistory lists status changes for an account, newest first.
+func GetStatusHistory(ctx context.Context, db *sql.DB, accountID int64) ([]models.StatusChange, error) {
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT h.id, h.account_id, h.changed_by, COALESCE(u.email, ''), h.from_status, h.to_status, h.note, h.changed_at
+		FROM status_history h LEFT JOIN users u ON u.id = h.changed_by
+		WHERE h.account_id = $1 ORDER BY h.changed_at DESC, h.id DESC;`,
+		accountID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query status history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []models.StatusChange{}
+	for rows.Next() {
+		var change models.StatusChange
+		var changed string
+		if err := rows.Scan(&change.ID, &change.AccountID, &change.ChangedBy, &change.ChangedByEmail, &change.FromStatus, &change.ToStatus, &change.Note, &changed); err != nil {
+			return nil, fmt.Errorf("scan status history: %w", err)
+		}
+		change.ChangedAt = parseSQLiteTime(changed)
+		history = append(history, change)
+	}
+	return history, rows.Err()
+}
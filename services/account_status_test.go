@@ -0,0 +1,106 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// resetStatusRules makes the next statusTransitions call read
+// ACCOUNT_STATUS_RULES again, and again after the test.
+func resetStatusRules(t *testing.T) {
+	t.Helper()
+	statusRulesOnce = sync.Once{}
+	t.Cleanup(func() { statusRulesOnce = sync.Once{} })
+}
+
+func TestCheckStatusTransitionDefaults(t *testing.T) {
+	t.Setenv("ACCOUNT_STATUS_RULES", "")
+	resetStatusRules(t)
+
+	cases := []struct {
+		from, to string
+		ok       bool
+	}{
+		{StatusPendingLogin, StatusActive, true},
+		{StatusActive, StatusActive, true},
+		{StatusActive, StatusPaymentFailed, true},
+		{StatusPaymentFailed, StatusOnHold, true},
+		{StatusExpired, StatusPendingLogin, true},
+		{StatusCancelled, StatusPendingLogin, true},
+		{StatusPendingLogin, StatusExpired, false},
+		{StatusInactive, StatusPaymentFailed, false},
+		{StatusCancelled, StatusActive, false},
+	}
+	for _, tc := range cases {
+		err := checkStatusTransition(tc.from, tc.to)
+		if tc.ok && err != nil {
+			t.Errorf("checkStatusTransition(%s, %s) = %v, want allowed", tc.from, tc.to, err)
+		}
+		if !tc.ok && !errors.Is(err, ErrIllegalStatusTransition) {
+			t.Errorf("checkStatusTransition(%s, %s) = %v, want ErrIllegalStatusTransition", tc.from, tc.to, err)
+		}
+	}
+}
+
+func TestCheckStatusTransitionRulesOverride(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	rules := `{"pending_login": ["active"], "active": ["cancelled"], "cancelled": []}`
+	if err := os.WriteFile(path, []byte(rules), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ACCOUNT_STATUS_RULES", path)
+	resetStatusRules(t)
+
+	cases := []struct {
+		from, to string
+		ok       bool
+	}{
+		{StatusPendingLogin, StatusActive, true},
+		{StatusActive, StatusCancelled, true},
+		{StatusActive, StatusPaymentFailed, false},
+		{StatusPendingLogin, StatusInactive, false},
+		{StatusCancelled, StatusPendingLogin, false},
+		{StatusExpired, StatusActive, false},
+	}
+	for _, tc := range cases {
+		err := checkStatusTransition(tc.from, tc.to)
+		if tc.ok && err != nil {
+			t.Errorf("checkStatusTransition(%s, %s) = %v, want allowed", tc.from, tc.to, err)
+		}
+		if !tc.ok && !errors.Is(err, ErrIllegalStatusTransition) {
+			t.Errorf("checkStatusTransition(%s, %s) = %v, want ErrIllegalStatusTransition", tc.from, tc.to, err)
+		}
+	}
+	if got := AllowedStatusTransitions(StatusCancelled); len(got) != 0 {
+		t.Errorf("AllowedStatusTransitions(cancelled) = %q, want none", got)
+	}
+}
+
+func TestLoadStatusRulesRejectsUnknownStatuses(t *testing.T) {
+	cases := []string{
+		`{"archived": ["active"]}`,
+		`{"active": ["archived"]}`,
+		`{"active": "cancelled"}`,
+	}
+	for _, rules := range cases {
+		path := filepath.Join(t.TempDir(), "rules.json")
+		if err := os.WriteFile(path, []byte(rules), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadStatusRules(path); err == nil {
+			t.Errorf("loadStatusRules(%s) succeeded, want an error", rules)
+		}
+	}
+}
+
+func TestStatusRulesFallBackToDefaults(t *testing.T) {
+	t.Setenv("ACCOUNT_STATUS_RULES", filepath.Join(t.TempDir(), "missing.json"))
+	resetStatusRules(t)
+
+	if err := checkStatusTransition(StatusActive, StatusPaymentFailed); err != nil {
+		t.Errorf("checkStatusTransition(active, payment_failed) = %v, want the default rules", err)
+	}
+}
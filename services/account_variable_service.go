@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidVariable = errors.New("invalid account variable")
+
+const (
+	maxAccountVariables     = 50
+	maxAccountVariableValue = 500
+)
+
+// GetAccountVariables returns the account's own tab URL variables.
+func GetAccountVariables(ctx context.Context, q querier, accountID int64) (map[string]string, error) {
+	rows, err := q.QueryContext(ctx, `SELECT name, value FROM account_variables WHERE account_id = $1;`, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("query account variables: %w", err)
+	}
+	defer rows.Close()
+
+	vars := map[string]string{}
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, fmt.Errorf("scan account variable: %w", err)
+		}
+		vars[name] = value
+	}
+	return vars, rows.Err()
+}
+
+// SetAccountVariables replaces the account's variables. A variable still
+// used by one of the account's tabs cannot be removed.
+func SetAccountVariables(ctx context.Context, db *sql.DB, accountID, userID int64, vars map[string]string) (map[string]string, error) {
+	cleaned, err := normalizeAccountVariables(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	err = withTx(ctx, db, func(tx *sql.Tx) error {
+		if err := lockOwnedAccount(ctx, tx, accountID, userID); err != nil {
+			return err
+		}
+		before, err := GetAccountVariables(ctx, tx, accountID)
+		if err != nil {
+			return err
+		}
+		tabs, err := GetTabs(ctx, tx, accountID)
+		if err != nil {
+			return err
+		}
+		for _, tab := range tabs {
+			if name, ok := undefinedTabVariable(tab.URL, cleaned); ok {
+				return fmt.Errorf("%w: %q is used by tab %q", ErrInvalidVariable, name, tab.Title)
+			}
+		}
+
+		if err := replaceAccountVariablesTx(ctx, tx, accountID, cleaned); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, "account.variables", "account", accountID, before, cleaned)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cleaned, nil
+}
+
+func normalizeAccountVariables(vars map[string]string) (map[string]string, error) {
+	if len(vars) > maxAccountVariables {
+		return nil, fmt.Errorf("%w: at most %d variables per account", ErrInvalidVariable, maxAccountVariables)
+	}
+	cleaned := make(map[string]string, len(vars))
+	for name, value := range vars {
+		name = strings.TrimSpace(name)
+		if !tabVariableName.MatchString(name) {
+			return nil, fmt.Errorf("%w: name %q must be lower-case letters, digits and _ and start with a letter", ErrInvalidVariable, name)
+		}
+		if isBuiltinTabVariable(name) {
+			return nil, fmt.Errorf("%w: %q is a built-in variable", ErrInvalidVariable, name)
+		}
+		if len(value) > maxAccountVariableValue {
+			return nil, fmt.Errorf("%w: value of %q is longer than %d characters", ErrInvalidVariable, name, maxAccountVariableValue)
+		}
+		cleaned[name] = value
+	}
+	return cleaned, nil
+}
+
+// replaceAccountVariablesTx swaps the account's variables for already
+// normalized ones. The caller must hold the account lock.
+func replaceAccountVariablesTx(ctx context.Context, tx *sql.Tx, accountID int64, vars map[string]string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM account_variables WHERE account_id = $1;`, accountID); err != nil {
+		return fmt.Errorf("clear account variables: %w", err)
+	}
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO account_variables (account_id, name, value) VALUES ($1, $2, $3);`,
+			accountID,
+			name,
+			vars[name],
+		); err != nil {
+			return fmt.Errorf("insert account variable: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkTabURLVariables rejects a tab URL using a variable the account does
+// not define. The caller must hold the account lock.
+func checkTabURLVariables(ctx context.Context, tx *sql.Tx, accountID int64, link string) error {
+	if _, ok := undefinedTabVariable(link, nil); !ok {
+		return nil
+	}
+	vars, err := GetAccountVariables(ctx, tx, accountID)
+	if err != nil {
+		return err
+	}
+	if name, ok := undefinedTabVariable(link, vars); ok {
+		return fmt.Errorf("%w: unknown variable {{%s}}; define it in the account's variables first", ErrInvalidTab, name)
+	}
+	return nil
+}
+
+// checkNewAccountTabURL rejects a tab URL for an account that is being
+// created: it has no variables yet, so only the built-in ones can be used.
+func checkNewAccountTabURL(link string) error {
+	if name, ok := undefinedTabVariable(link, nil); ok {
+		return fmt.Errorf("%w: unknown variable {{%s}}; a new account only has {{%s}}", ErrInvalidTab, name, strings.Join(builtinTabVariables, "}}, {{"))
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"netflix_central/models"
+)
+
+// Actor describes who performed a request. It travels on the request context
+// so services can write audit events without extra parameters.
+type Actor struct {
+	UserID    int64
+	Email     string
+	IP        string
+	UserAgent string
+}
+
+type actorKey struct{}
+
+// WithActor returns a context carrying the actor.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor stored on the context, if any.
+func ActorFrom(ctx context.Context) Actor {
+	actor, _ := ctx.Value(actorKey{}).(Actor)
+	return actor
+}
+
+// withActorUser fills in the user on the context actor, for requests such as
+// login where the user is only known once the service has looked it up.
+func withActorUser(ctx context.Context, userID int64, email string) context.Context {
+	actor := ActorFrom(ctx)
+	actor.UserID, actor.Email = userID, email
+	return WithActor(ctx, actor)
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx, so audit rows can be
+// written inside the transaction of the change they describe.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// AuditFilter narrows an audit query. Zero values mean "any".
+type AuditFilter struct {
+	Action     string
+	TargetType string
+	TargetID   int64
+	Since      time.Time
+	Until      time.Time
+	BeforeID   int64
+	Limit      int
+}
+
+const (
+	defaultAuditPageSize = 100
+	maxAuditPageSize     = 500
+)
+
+// recordAudit appends one event. before and after are marshalled to JSON and
+// only the top-level fields that differ are kept as the diff.
+func recordAudit(ctx context.Context, ex execer, action, targetType string, targetID int64, before, after any) error {
+	actor := ActorFrom(ctx)
+	changes, err := auditDiff(before, after)
+	if err != nil {
+		return fmt.Errorf("audit diff: %w", err)
+	}
+
+	var userID any
+	if actor.UserID != 0 {
+		userID = actor.UserID
+	}
+
+	if _, err := ex.ExecContext(
+		ctx,
+		`INSERT INTO audit_events (user_id, actor_email, action, target_type, target_id, changes, ip, user_agent, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);`,
+		userID,
+		actor.Email,
+		action,
+		targetType,
+		targetID,
+		changes,
+		actor.IP,
+		actor.UserAgent,
+		time.Now().UTC().Format(time.RFC3339Nano),
+	); err != nil {
+		return fmt.Errorf("insert audit event: %w", err)
+	}
+	return nil
+}
+
+// auditDiff returns a JSON object mapping changed fields to {"from", "to"}.
+func auditDiff(before, after any) ([]byte, error) {
+	from, err := toFieldMap(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := toFieldMap(after)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := map[string]map[string]any{}
+	for key, value := range to {
+		if old, ok := from[key]; !ok || !reflect.DeepEqual(old, value) {
+			diff[key] = map[string]any{"from": from[key], "to": value}
+		}
+	}
+	for key, value := range from {
+		if _, ok := to[key]; !ok {
+			diff[key] = map[string]any{"from": value, "to": nil}
+		}
+	}
+	return json.Marshal(diff)
+}
+
+func toFieldMap(v any) (map[string]any, error) {
+	if v == nil {
+		return map[string]any{}, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]any{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// ListAuditEvents returns one page of the user's events matching the filter,
+// newest first, and the BeforeID of the next page (0 when there is none).
+func ListAuditEvents(ctx context.Context, db *sql.DB, userID int64, f AuditFilter) ([]models.AuditEvent, int64, error) {
+	if f.Limit <= 0 {
+		f.Limit = defaultAuditPageSize
+	}
+	if f.Limit > maxAuditPageSize {
+		f.Limit = maxAuditPageSize
+	}
+	query, args := auditQuery(userID, f)
+	// Fetch one extra row to know whether another page follows.
+	query += fmt.Sprintf(" LIMIT %d;", f.Limit+1)
+
+	events := []models.AuditEvent{}
+	err := eachAuditEvent(ctx, db, query, args, func(e models.AuditEvent) error {
+		events = append(events, e)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var next int64
+	if len(events) > f.Limit {
+		events = events[:f.Limit]
+		next = events[len(events)-1].ID
+	}
+	return events, next, nil
+}
+
+// ExportAuditEvents streams every matching event to fn, newest first.
+func ExportAuditEvents(ctx context.Context, db *sql.DB, userID int64, f AuditFilter, fn func(models.AuditEvent) error) error {
+	query, args := auditQuery(userID, f)
+	return eachAuditEvent(ctx, db, query+";", args, fn)
+}
+
+func auditQuery(userID int64, f AuditFilter) (string, []any) {
+	conds := []string{"user_id = $1"}
+	args := []any{userID}
+	addArg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if action := strings.TrimSpace(f.Action); action != "" {
+		// "account" matches every account.* action.
+		if strings.Contains(action, ".") {
+			conds = append(conds, "action = "+addArg(action))
+		} else {
+			conds = append(conds, "action LIKE "+addArg(escapeLike(action)+".%"))
+		}
+	}
+	if f.TargetType != "" {
+		conds = append(conds, "target_type = "+addArg(f.TargetType))
+	}
+	if f.TargetID != 0 {
+		conds = append(conds, "target_id = "+addArg(f.TargetID))
+	}
+	if !f.Since.IsZero() {
+		conds = append(conds, "created_at >= "+addArg(f.Since.UTC().Format(time.RFC3339Nano)))
+	}
+	if !f.Until.IsZero() {
+		conds = append(conds, "created_at < "+addArg(f.Until.UTC().Format(time.RFC3339Nano)))
+	}
+	if f.BeforeID != 0 {
+		conds = append(conds, "id < "+addArg(f.BeforeID))
+	}
+
+	return `SELECT id, user_id, actor_email, action, target_type, target_id, changes, ip, user_agent, created_at
+		FROM audit_events WHERE ` + strings.Join(conds, " AND ") + ` ORDER BY id DESC`, args
+}
+
+func eachAuditEvent(ctx context.Context, db *sql.DB, query string, args []any, fn func(models.AuditEvent) error) error {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("query audit events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.AuditEvent
+		var userID sql.NullInt64
+		var changes []byte
+		var created string
+		if err := rows.Scan(&e.ID, &userID, &e.ActorEmail, &e.Action, &e.TargetType, &e.TargetID, &changes, &e.IP, &e.UserAgent, &created); err != nil {
+			return fmt.Errorf("scan audit event: %w", err)
+		}
+		if userID.Valid {
+			e.UserID = &userID.Int64
+		}
+		e.Changes = json.RawMessage(changes)
+		e.CreatedAt = parseSQLiteTime(created)
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// Chrome has no command line switch or DevTools command for tab groups or
+// pinned tabs, so the launcher loads a small helper extension into the
+// session and calls its arrangeTabs function over the DevTools protocol once
+// the window is open. Branded Chrome 137+ ignores --load-extension, so for
+// those versions, and when the version cannot be told, the launcher neither
+// loads the extension nor opens the DevTools port, and reports that the tabs
+// were not arranged.
+
+const (
+	devToolsOrigin     = "http://127.0.0.1"
+	arrangeTabsTimeout = 20 * time.Second
+	// lastLoadExtensionChrome is the last branded Chrome major version that
+	// honours --load-extension.
+	lastLoadExtensionChrome = 136
+)
+
+// chromeTabPlan is passed to the helper extension. Tab indexes refer to the
+// order of the URLs on the command line.
+type chromeTabPlan struct {
+	Groups []chromeTabGroup `json:"groups"`
+	Pinned []int            `json:"pinned"`
+	Active int              `json:"active"`
+}
+
+type chromeTabGroup struct {
+	Title     string `json:"title"`
+	Color     string `json:"color"`
+	Collapsed bool   `json:"collapsed"`
+	Tabs      []int  `json:"tabs"`
+}
+
+const launchHelperManifest = `{
+  "manifest_version": 3,
+  "name": "Netflix Central launch helper",
+  "version": "1.0",
+  "permissions": ["tabs", "tabGroups"],
+  "background": { "service_worker": "background.js" }
+}
+`
+
+const launchHelperBackground = `// Called by the Netflix Central launcher over DevTools to pin, group and
+// focus the tabs of the window it just opened.
+async function arrangeTabs(plan, count) {
+  let win;
+  for (let attempt = 0; attempt < 50; attempt++) {
+    const windows = await chrome.windows.getAll({ populate: true, windowTypes: ["normal"] });
+    win = windows.reduce((a, b) => (a && a.id > b.id ? a : b), undefined);
+    if (win && win.tabs.length >= count) break;
+    await new Promise((resolve) => setTimeout(resolve, 200));
+  }
+  if (!win) return 0;
+  const offset = Math.max(win.tabs.length - count, 0);
+  const tabIds = (indexes) => indexes
+    .map((i) => win.tabs[offset + i])
+    .filter((tab) => tab !== undefined)
+    .map((tab) => tab.id);
+
+  for (const tabId of tabIds(plan.pinned || [])) {
+    await chrome.tabs.update(tabId, { pinned: true });
+  }
+  let created = 0;
+  for (const group of plan.groups || []) {
+    const ids = tabIds(group.tabs);
+    if (ids.length === 0) continue;
+    const groupId = await chrome.tabs.group({ tabIds: ids, createProperties: { windowId: win.id } });
+    await chrome.tabGroups.update(groupId, { title: group.title, color: group.color, collapsed: group.collapsed });
+    created++;
+  }
+  const [active] = tabIds([plan.active]);
+  if (active !== undefined) {
+    await chrome.tabs.update(active, { active: true });
+  }
+  return created;
+}
+`
+
+// checkLaunchExtensionSupport reports why the Chrome at chromePath cannot load
+// the helper extension, or nil when it can. The Windows installer keeps each
+// version in a directory named after it, e.g. 136.0.7103.114, next to
+// chrome.exe; the newest one is what runs.
+func checkLaunchExtensionSupport(chromePath string) error {
+	entries, err := os.ReadDir(filepath.Dir(chromePath))
+	if err != nil {
+		return fmt.Errorf("read chrome version: %w", err)
+	}
+	major := 0
+	for _, entry := range entries {
+		parts := strings.Split(entry.Name(), ".")
+		if !entry.IsDir() || len(parts) != 4 {
+			continue
+		}
+		if n, err := strconv.Atoi(parts[0]); err == nil {
+			major = max(major, n)
+		}
+	}
+	switch {
+	case major == 0:
+		return errors.New("chrome version is unknown")
+	case major > lastLoadExtensionChrome:
+		return fmt.Errorf("chrome %d ignores --load-extension", major)
+	}
+	return nil
+}
+
+// ensureLaunchExtension writes the helper extension next to the Chrome
+// profiles and returns its directory.
+func ensureLaunchExtension() (string, error) {
+	workdir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("resolve working directory: %w", err)
+	}
+	dir := filepath.Join(workdir, "chrome_extensions", "launch-helper")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", fmt.Errorf("create extension dir: %w", err)
+	}
+	files := map[string]string{"manifest.json": launchHelperManifest, "background.js": launchHelperBackground}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			return "", fmt.Errorf("write extension: %w", err)
+		}
+	}
+	return dir, nil
+}
+
+// arrangeChromeTabs waits for the session's DevTools endpoint and asks the
+// helper extension to apply the plan. It only logs failures: the session is
+// usable without its groups and pinned tabs.
+func arrangeChromeTabs(accountID int64, profileDir string, plan chromeTabPlan, tabCount int) {
+	deadline := time.Now().Add(arrangeTabsTimeout)
+	var lastErr error
+	for time.Now().Before(deadline) {
+		target, err := findLaunchHelperWorker(profileDir)
+		if err == nil {
+			created, err := evaluateArrangeTabs(target, plan, tabCount)
+			if err != nil {
+				log.Printf("launch account %d: arrange tabs: %v", accountID, err)
+				return
+			}
+			log.Printf("launch account %d: arranged tabs, %d tab groups", accountID, created)
+			return
+		}
+		lastErr = err
+		time.Sleep(500 * time.Millisecond)
+	}
+	log.Printf("launch account %d: tabs not arranged: %v", accountID, lastErr)
+}
+
+// findLaunchHelperWorker returns the DevTools websocket URL of the helper
+// extension's service worker.
+func findLaunchHelperWorker(profileDir string) (string, error) {
+	// Chrome writes the port it picked for --remote-debugging-port=0 to the
+	// first line of DevToolsActivePort in the profile directory.
+	raw, err := os.ReadFile(filepath.Join(profileDir, "DevToolsActivePort"))
+	if err != nil {
+		return "", fmt.Errorf("read devtools port: %w", err)
+	}
+	port, err := strconv.Atoi(strings.TrimSpace(strings.SplitN(string(raw), "\n", 2)[0]))
+	if err != nil {
+		return "", fmt.Errorf("parse devtools port: %w", err)
+	}
+
+	client := http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Get(devToolsOrigin + ":" + strconv.Itoa(port) + "/json/list")
+	if err != nil {
+		return "", fmt.Errorf("list devtools targets: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var targets []struct {
+		Type                 string `json:"type"`
+		URL                  string `json:"url"`
+		WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&targets); err != nil {
+		return "", fmt.Errorf("decode devtools targets: %w", err)
+	}
+	for _, target := range targets {
+		if target.Type == "service_worker" && strings.HasPrefix(target.URL, "chrome-extension://") &&
+			strings.HasSuffix(target.URL, "/background.js") && target.WebSocketDebuggerURL != "" {
+			return target.WebSocketDebuggerURL, nil
+		}
+	}
+	return "", errors.New("launch helper extension is not running")
+}
+
+// evaluateArrangeTabs runs arrangeTabs in the extension's service worker and
+// returns the number of groups it created.
+func evaluateArrangeTabs(wsURL string, plan chromeTabPlan, tabCount int) (int, error) {
+	arg, err := json.Marshal(plan)
+	if err != nil {
+		return 0, err
+	}
+
+	config, err := websocket.NewConfig(wsURL, devToolsOrigin)
+	if err != nil {
+		return 0, err
+	}
+	config.Dialer = &net.Dialer{Timeout: 2 * time.Second}
+	conn, err := websocket.DialConfig(config)
+	if err != nil {
+		return 0, fmt.Errorf("connect devtools: %w", err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(arrangeTabsTimeout)); err != nil {
+		return 0, err
+	}
+
+	request := map[string]any{
+		"id":     1,
+		"method": "Runtime.evaluate",
+		"params": map[string]any{
+			"expression":    fmt.Sprintf("arrangeTabs(%s, %d)", arg, tabCount),
+			"awaitPromise":  true,
+			"returnByValue": true,
+		},
+	}
+	if err := websocket.JSON.Send(conn, request); err != nil {
+		return 0, fmt.Errorf("send devtools command: %w", err)
+	}
+
+	for {
+		var response struct {
+			ID     int `json:"id"`
+			Result struct {
+				Result struct {
+					Value json.RawMessage `json:"value"`
+				} `json:"result"`
+				ExceptionDetails *struct {
+					Text      string `json:"text"`
+					Exception struct {
+						Description string `json:"description"`
+					} `json:"exception"`
+				} `json:"exceptionDetails"`
+			} `json:"result"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := websocket.JSON.Receive(conn, &response); err != nil {
+			return 0, fmt.Errorf("read devtools response: %w", err)
+		}
+		if response.ID != 1 {
+			continue
+		}
+		if response.Error != nil {
+			return 0, errors.New(response.Error.Message)
+		}
+		if details := response.Result.ExceptionDetails; details != nil {
+			if details.Exception.Description != "" {
+				return 0, errors.New(details.Exception.Description)
+			}
+			return 0, errors.New(details.Text)
+		}
+		var created int
+		_ = json.Unmarshal(response.Result.Result.Value, &created)
+		return created, nil
+	}
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckLaunchExtensionSupport(t *testing.T) {
+	cases := []struct {
+		name     string
+		versions []string
+		ok       bool
+	}{
+		{"supported", []string{"136.0.7103.114"}, true},
+		{"ignores load-extension", []string{"137.0.7151.69"}, false},
+		{"newest version runs", []string{"136.0.7103.114", "137.0.7151.69"}, false},
+		{"unknown version", nil, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			chromePath := filepath.Join(dir, "chrome.exe")
+			if err := os.WriteFile(chromePath, nil, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			for _, v := range append(tc.versions, "SetupMetrics") {
+				if err := os.Mkdir(filepath.Join(dir, v), 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := checkLaunchExtensionSupport(chromePath)
+			if (err == nil) != tc.ok {
+				t.Fatalf("checkLaunchExtensionSupport = %v, want ok %v", err, tc.ok)
+			}
+		})
+	}
+}
@@ -11,32 +11,132 @@ import (
 )
 
 // LaunchChrome opens Chrome with the provided account and tabs using a persistent profile directory.
-func LaunchChrome(account models.Account, tabs []models.Tab) error {
+// The session counts against SESSION_MAX_CONCURRENT until the browser exits.
+// Tab groups, pinned tabs and background tabs are applied to the new window
+// afterwards, see arrangeChromeTabs; the returned warnings say which of them
+// could not be applied, and which tabs were not opened. Placeholders in tab URLs are filled in from the
+// account and vars, its own variables. The returned channel reports when a
+// newly started browser exits, see sessionRegistry.launch.
+func LaunchChrome(account models.Account, tabs []models.Tab, groups []models.TabGroup, vars map[string]string) (<-chan error, []string, error) {
+	return runningSessions.launch(account, tabs, groups, vars, LoadSessionConfig().MaxConcurrent)
+}
+
+func startChrome(account models.Account, tabs []models.Tab, groups []models.TabGroup, vars map[string]string) (*exec.Cmd, []string, error) {
 	chromePath, err := findChromePath()
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	profileDir, err := resolveProfileDir(account.ChromeProfile)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	if err := os.MkdirAll(profileDir, os.ModePerm); err != nil {
-		return fmt.Errorf("create profile dir: %w", err)
+		return nil, nil, fmt.Errorf("create profile dir: %w", err)
 	}
 
+	// "--" ends Chrome's switch parsing, so nothing after it is read as a
+	// flag. URLs are checked again after rendering, and because rows may
+	// predate validation.
 	args := []string{
 		"--user-data-dir=" + profileDir,
 		"--profile-directory=Default",
 		"--new-window",
 	}
+	// Pinned tabs go first, where Chrome keeps them anyway, so the indexes
+	// in the plan stay valid once they are pinned.
+	ordered := make([]models.Tab, 0, len(tabs))
+	for _, tab := range tabs {
+		if tab.Pinned {
+			ordered = append(ordered, tab)
+		}
+	}
 	for _, tab := range tabs {
-		args = append(args, tab.URL)
+		if !tab.Pinned {
+			ordered = append(ordered, tab)
+		}
+	}
+
+	// Saving checks URLs and their variables, so a tab only fails here
+	// when it predates those checks or TAB_URL_ALLOWLIST changed. It is left
+	// out and reported rather than failing the whole launch.
+	values := tabVariables(account, vars)
+	var links, warnings []string
+	plan := chromeTabPlan{Active: -1}
+	indexes := map[int64][]int{}
+	for _, tab := range ordered {
+		link, err := renderTabURL(tab.URL, values)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("tab %q was not opened: %v", tab.Title, err))
+			continue
+		}
+		// Chrome cannot group pinned tabs, so pinning wins.
+		if tab.Pinned {
+			plan.Pinned = append(plan.Pinned, len(links))
+		} else if tab.GroupID != nil {
+			indexes[*tab.GroupID] = append(indexes[*tab.GroupID], len(links))
+		}
+		if plan.Active < 0 && !tab.OpenInBackground {
+			plan.Active = len(links)
+		}
+		links = append(links, link)
+	}
+	// Chrome focuses the first tab; keep it when every tab is a background tab.
+	plan.Active = max(plan.Active, 0)
+
+	for _, group := range groups {
+		if len(indexes[group.ID]) > 0 {
+			plan.Groups = append(plan.Groups, chromeTabGroup{Title: group.Name, Color: group.Color, Collapsed: group.Collapsed, Tabs: indexes[group.ID]})
+		}
+	}
+	arrange := len(plan.Groups) > 0 || len(plan.Pinned) > 0 || plan.Active > 0
+	if arrange {
+		extensionDir := ""
+		err := checkLaunchExtensionSupport(chromePath)
+		if err == nil {
+			extensionDir, err = ensureLaunchExtension()
+		}
+		if err != nil {
+			// Without the extension nobody would use the DevTools port, so
+			// it stays closed.
+			arrange = false
+			warnings = append(warnings, unarrangedTabWarnings(plan, err)...)
+		} else {
+			args = append(args,
+				"--remote-debugging-port=0",
+				"--remote-allow-origins="+devToolsOrigin,
+				"--load-extension="+extensionDir,
+			)
+		}
 	}
+	args = append(append(args, "--"), links...)
 
 	cmd := exec.Command(chromePath, args...) // #nosec G204 - user-controlled paths are validated above.
-	return cmd.Start()
+	if err := cmd.Start(); err != nil {
+		return nil, nil, err
+	}
+	if arrange {
+		go arrangeChromeTabs(account.ID, profileDir, plan, len(links))
+	}
+	return cmd, warnings, nil
+}
+
+// unarrangedTabWarnings lists what of plan the launch leaves out because the
+// helper extension cannot run.
+func unarrangedTabWarnings(plan chromeTabPlan, reason error) []string {
+	var warnings []string
+	if len(plan.Groups) > 0 {
+		warnings = append(warnings, fmt.Sprintf("tab groups were not applied: %v", reason))
+	}
+	if len(plan.Pinned) > 0 {
+		warnings = append(warnings, fmt.Sprintf("pinned tabs were opened unpinned: %v", reason))
+	}
+	// Chrome focuses the first tab, which is then a background tab.
+	if plan.Active > 0 {
+		warnings = append(warnings, fmt.Sprintf("background tabs were not applied, the first tab has focus: %v", reason))
+	}
+	return warnings
 }
 
 func findChromePath() (string, error) {
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"netflix_central/models"
+)
+
+var ErrInvalidCustomer = errors.New("invalid customer")
+
+const customerColumns = `id, user_id, name, contact, notes, created_at`
+
+// CustomerInput carries the editable customer fields.
+type CustomerInput struct {
+	Name    string
+	Contact string
+	Notes   string
+}
+
+func ListCustomers(ctx context.Context, db *sql.DB, userID int64, search string) ([]models.Customer, error) {
+	query := `SELECT ` + customerColumns + ` FROM customers WHERE user_id = $1`
+	args := []any{userID}
+	if search = strings.TrimSpace(search); search != "" {
+		query += ` AND (name ILIKE $2 OR contact ILIKE $2)`
+		args = append(args, "%"+escapeLike(search)+"%")
+	}
+
+	rows, err := db.QueryContext(ctx, query+` ORDER BY LOWER(name) ASC, id ASC;`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query customers: %w", err)
+	}
+	defer rows.Close()
+
+	customers := []models.Customer{}
+	for rows.Next() {
+		customer, err := scanCustomer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan customer: %w", err)
+		}
+		customers = append(customers, customer)
+	}
+	return customers, rows.Err()
+}
+
+func GetCustomer(ctx context.Context, db *sql.DB, id, userID int64) (models.Customer, error) {
+	return scanCustomer(db.QueryRowContext(ctx, `SELECT `+customerColumns+` FROM customers WHERE id = $1 AND user_id = $2;`, id, userID))
+}
+
+func CreateCustomer(ctx context.Context, db *sql.DB, userID int64, in CustomerInput) (models.Customer, error) {
+	in, err := normalizeCustomer(in)
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	createdAt := time.Now().UTC().Format(time.RFC3339Nano)
+	var id int64
+	if err := db.QueryRowContext(
+		ctx,
+		`INSERT INTO customers (user_id, name, contact, notes, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id;`,
+		userID,
+		in.Name,
+		in.Contact,
+		in.Notes,
+		createdAt,
+	).Scan(&id); err != nil {
+		return models.Customer{}, fmt.Errorf("insert customer: %w", err)
+	}
+
+	return models.Customer{ID: id, UserID: userID, Name: in.Name, Contact: in.Contact, Notes: in.Notes, CreatedAt: parseSQLiteTime(createdAt)}, nil
+}
+
+func UpdateCustomer(ctx context.Context, db *sql.DB, id, userID int64, in CustomerInput) (models.Customer, error) {
+	in, err := normalizeCustomer(in)
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	result, err := db.ExecContext(
+		ctx,
+		`UPDATE customers SET name = $1, contact = $2, notes = $3 WHERE id = $4 AND user_id = $5;`,
+		in.Name,
+		in.Contact,
+		in.Notes,
+		id,
+		userID,
+	)
+	if err != nil {
+		return models.Customer{}, fmt.Errorf("update customer: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return models.Customer{}, sql.ErrNoRows
+	}
+
+	return GetCustomer(ctx, db, id, userID)
+}
+
+// DeleteCustomer removes a customer together with their seat assignments.
+func DeleteCustomer(ctx context.Context, db *sql.DB, id, userID int64) error {
+	result, err := db.ExecContext(ctx, `DELETE FROM customers WHERE id = $1 AND user_id = $2;`, id, userID)
+	if err != nil {
+		return fmt.Errorf("delete customer: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func normalizeCustomer(in CustomerInput) (CustomerInput, error) {
+	in.Name = strings.TrimSpace(in.Name)
+	in.Contact = strings.TrimSpace(in.Contact)
+	in.Notes = strings.TrimSpace(in.Notes)
+	if in.Name == "" {
+		return in, fmt.Errorf("%w: name is required", ErrInvalidCustomer)
+	}
+	return in, nil
+}
+
+func scanCustomer(row rowScanner) (models.Customer, error) {
+	var customer models.Customer
+	var created string
+	if err := row.Scan(&customer.ID, &customer.UserID, &customer.Name, &customer.Contact, &customer.Notes, &created); err != nil {
+		return customer, err
+	}
+	customer.CreatedAt = parseSQLiteTime(created)
+	return customer, nil
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"netflix_central/models"
+)
+
+var ErrInvalidFolder = errors.New("invalid folder")
+
+// maxFolderDepth is how deep folders may be nested. Creating and moving
+// folders keep to it, so the recursive folder queries, which stop there to
+// never loop on a parent cycle in the data, always see the whole tree.
+const maxFolderDepth = 64
+
+func ListFolders(ctx context.Context, db *sql.DB, userID int64) ([]models.Folder, error) {
+	rows, err := db.QueryContext(ctx, `SELECT id, user_id, parent_id, name, created_at FROM folders WHERE user_id = $1 ORDER BY LOWER(name) ASC;`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("query folders: %w", err)
+	}
+	defer rows.Close()
+
+	folders := []models.Folder{}
+	for rows.Next() {
+		folder, err := scanFolder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan folder: %w", err)
+		}
+		folders = append(folders, folder)
+	}
+	return folders, rows.Err()
+}
+
+func GetFolder(ctx context.Context, db querier, id, userID int64) (models.Folder, error) {
+	return scanFolder(db.QueryRowContext(ctx, `SELECT id, user_id, parent_id, name, created_at FROM folders WHERE id = $1 AND user_id = $2;`, id, userID))
+}
+
+func CreateFolder(ctx context.Context, db *sql.DB, userID int64, name string, parentID *int64) (models.Folder, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return models.Folder{}, fmt.Errorf("%w: name is required", ErrInvalidFolder)
+	}
+
+	createdAt := time.Now().UTC().Format(time.RFC3339Nano)
+	var id int64
+	err := withTx(ctx, db, func(tx *sql.Tx) error {
+		if parentID != nil {
+			// Lock the tree so a concurrent move cannot push the parent
+			// deeper before the insert.
+			if err := lockUserFolders(ctx, tx, userID); err != nil {
+				return err
+			}
+			if err := ensureFolderOwned(ctx, tx, *parentID, userID); err != nil {
+				return err
+			}
+			if err := checkFolderDepth(ctx, tx, *parentID, 1); err != nil {
+				return err
+			}
+		}
+		if err := tx.QueryRowContext(
+			ctx,
+			`INSERT INTO folders (user_id, parent_id, name, created_at) VALUES ($1, $2, $3, $4) RETURNING id;`,
+			userID,
+			parentID,
+			name,
+			createdAt,
+		).Scan(&id); err != nil {
+			return fmt.Errorf("insert folder: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Folder{}, err
+	}
+
+	return models.Folder{ID: id, UserID: userID, ParentID: parentID, Name: name, CreatedAt: parseSQLiteTime(createdAt)}, nil
+}
+
+// UpdateFolder renames or moves a folder. Moving a folder below itself or one
+// of its descendants is rejected. The user's folders stay locked from the
+// cycle check to the update, so concurrent moves cannot build a cycle.
+func UpdateFolder(ctx context.Context, db *sql.DB, id, userID int64, name string, parentID *int64) (models.Folder, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return models.Folder{}, fmt.Errorf("%w: name is required", ErrInvalidFolder)
+	}
+
+	var folder models.Folder
+	err := withTx(ctx, db, func(tx *sql.Tx) error {
+		if err := lockUserFolders(ctx, tx, userID); err != nil {
+			return err
+		}
+		before, err := GetFolder(ctx, tx, id, userID)
+		if err != nil {
+			return err
+		}
+
+		if parentID != nil {
+			if err := ensureFolderOwned(ctx, tx, *parentID, userID); err != nil {
+				return err
+			}
+			var cycle bool
+			if err := tx.QueryRowContext(
+				ctx,
+				`WITH RECURSIVE ancestors AS (
+					SELECT id, parent_id, 1 AS depth FROM folders WHERE id = $1
+					UNION ALL
+					SELECT f.id, f.parent_id, a.depth + 1 FROM folders f JOIN ancestors a ON f.id = a.parent_id
+					WHERE a.depth < $3
+				)
+				SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $2);`,
+				*parentID,
+				id,
+				maxFolderDepth,
+			).Scan(&cycle); err != nil {
+				return fmt.Errorf("check folder cycle: %w", err)
+			}
+			if cycle {
+				return fmt.Errorf("%w: a folder cannot be moved into itself", ErrInvalidFolder)
+			}
+
+			var height int
+			if err := tx.QueryRowContext(
+				ctx,
+				`WITH RECURSIVE subtree AS (
+					SELECT id, 1 AS depth FROM folders WHERE id = $1
+					UNION ALL
+					SELECT f.id, s.depth + 1 FROM folders f JOIN subtree s ON f.parent_id = s.id
+					WHERE s.depth <= $2
+				)
+				SELECT MAX(depth) FROM subtree;`,
+				id,
+				maxFolderDepth,
+			).Scan(&height); err != nil {
+				return fmt.Errorf("measure folder subtree: %w", err)
+			}
+			if err := checkFolderDepth(ctx, tx, *parentID, height); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE folders SET name = $1, parent_id = $2 WHERE id = $3;`, name, parentID, id); err != nil {
+			return fmt.Errorf("update folder: %w", err)
+		}
+		folder = before
+		folder.Name, folder.ParentID = name, parentID
+		return recordAudit(ctx, tx, "folder.update", "folder", id, before, folder)
+	})
+	if err != nil {
+		return models.Folder{}, err
+	}
+	return folder, nil
+}
+
+// checkFolderDepth rejects putting levels levels of folders below parentID
+// when that would nest them deeper than maxFolderDepth.
+func checkFolderDepth(ctx context.Context, tx *sql.Tx, parentID int64, levels int) error {
+	var depth int
+	if err := tx.QueryRowContext(
+		ctx,
+		`WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id, 1 AS depth FROM folders WHERE id = $1
+			UNION ALL
+			SELECT f.id, f.parent_id, a.depth + 1 FROM folders f JOIN ancestors a ON f.id = a.parent_id
+			WHERE a.depth <= $2
+		)
+		SELECT MAX(depth) FROM ancestors;`,
+		parentID,
+		maxFolderDepth,
+	).Scan(&depth); err != nil {
+		return fmt.Errorf("measure folder depth: %w", err)
+	}
+	if depth+levels > maxFolderDepth {
+		return fmt.Errorf("%w: folders can be nested at most %d deep", ErrInvalidFolder, maxFolderDepth)
+	}
+	return nil
+}
+
+// lockUserFolders locks all of the user's folders, serialising changes to
+// the folder tree.
+func lockUserFolders(ctx context.Context, tx *sql.Tx, userID int64) error {
+	if _, err := tx.ExecContext(ctx, `SELECT id FROM folders WHERE user_id = $1 FOR UPDATE;`, userID); err != nil {
+		return fmt.Errorf("lock folders: %w", err)
+	}
+	return nil
+}
+
+// DeleteFolder removes a folder and moves its subfolders and accounts up to its parent.
+func DeleteFolder(ctx context.Context, db *sql.DB, id, userID int64) error {
+	return withTx(ctx, db, func(tx *sql.Tx) error {
+		if err := lockUserFolders(ctx, tx, userID); err != nil {
+			return err
+		}
+		var parentID sql.NullInt64
+		if err := tx.QueryRowContext(ctx, `SELECT parent_id FROM folders WHERE id = $1 AND user_id = $2 FOR UPDATE;`, id, userID).Scan(&parentID); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE folders SET parent_id = $1 WHERE parent_id = $2 AND user_id = $3;`, parentID, id, userID); err != nil {
+			return fmt.Errorf("move subfolders: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE accounts SET folder_id = $1, version = version + 1 WHERE folder_id = $2 AND user_id = $3;`, parentID, id, userID); err != nil {
+			return fmt.Errorf("move folder accounts: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM folders WHERE id = $1 AND user_id = $2;`, id, userID); err != nil {
+			return fmt.Errorf("delete folder: %w", err)
+		}
+		return nil
+	})
+}
+
+// SetAccountFolder moves an account into a folder, or out of any folder when folderID is nil.
+func SetAccountFolder(ctx context.Context, db *sql.DB, accountID, userID int64, folderID *int64) (models.Account, error) {
+	if folderID != nil {
+		if err := ensureFolderOwned(ctx, db, *folderID, userID); err != nil {
+			return models.Account{}, err
+		}
+	}
+
+	err := withTx(ctx, db, func(tx *sql.Tx) error {
+		var before sql.NullInt64
+		if err := tx.QueryRowContext(
+			ctx,
+			`SELECT folder_id FROM accounts WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL FOR UPDATE;`,
+			accountID,
+			userID,
+		).Scan(&before); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE accounts SET folder_id = $1, version = version + 1 WHERE id = $2;`, folderID, accountID); err != nil {
+			return fmt.Errorf("set account folder: %w", err)
+		}
+
+		var from *int64
+		if before.Valid {
+			from = &before.Int64
+		}
+		return recordAudit(ctx, tx, "account.folder", "account", accountID, map[string]any{"folder_id": from}, map[string]any{"folder_id": folderID})
+	})
+	if err != nil {
+		return models.Account{}, err
+	}
+	return GetAccount(ctx, db, accountID, userID)
+}
+
+func ensureFolderOwned(ctx context.Context, db querier, id, userID int64) error {
+	if _, err := GetFolder(ctx, db, id, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: unknown folder id", ErrInvalidFolder)
+		}
+		return err
+	}
+	return nil
+}
+
+func scanFolder(row rowScanner) (models.Folder, error) {
+	var folder models.Folder
+	var parentID sql.NullInt64
+	var created string
+	if err := row.Scan(&folder.ID, &folder.UserID, &parentID, &folder.Name, &created); err != nil {
+		return folder, err
+	}
+	if parentID.Valid {
+		folder.ParentID = &parentID.Int64
+	}
+	folder.CreatedAt = parseSQLiteTime(created)
+	return folder, nil
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFolderNestingIsCappedAtMaxDepth(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	userID := createTestUser(t, db)
+
+	var parent *int64
+	var root int64
+	for depth := 1; depth <= maxFolderDepth; depth++ {
+		folder, err := CreateFolder(ctx, db, userID, "level", parent)
+		if err != nil {
+			t.Fatalf("CreateFolder at depth %d: %v", depth, err)
+		}
+		if depth == 1 {
+			root = folder.ID
+		}
+		id := folder.ID
+		parent = &id
+	}
+	if _, err := CreateFolder(ctx, db, userID, "too deep", parent); !errors.Is(err, ErrInvalidFolder) {
+		t.Fatalf("CreateFolder below max depth = %v, want %v", err, ErrInvalidFolder)
+	}
+
+	other, err := CreateFolder(ctx, db, userID, "other root", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UpdateFolder(ctx, db, root, userID, "level", &other.ID); !errors.Is(err, ErrInvalidFolder) {
+		t.Fatalf("moving a full-depth tree below another folder = %v, want %v", err, ErrInvalidFolder)
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"netflix_central/models"
+)
+
+const maxLaunchPageSize = 200
+
+// ListLaunches returns the account's most recent launches, newest first.
+func ListLaunches(ctx context.Context, db *sql.DB, accountID, userID int64, limit int) ([]models.Launch, error) {
+	if limit <= 0 || limit > maxLaunchPageSize {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, maxLaunchPageSize)
+	}
+
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT l.id, l.account_id, l.user_id, u.email, l.started_at, l.ended_at,
+			EXTRACT(EPOCH FROM l.ended_at - l.started_at)::BIGINT, l.outcome, l.error
+		FROM launches l
+		JOIN accounts a ON a.id = l.account_id
+		JOIN users u ON u.id = l.user_id
+		WHERE l.account_id = $1 AND a.user_id = $2
+		ORDER BY l.started_at DESC, l.id DESC
+		LIMIT $3;`,
+		accountID,
+		userID,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query launches: %w", err)
+	}
+	defer rows.Close()
+
+	launches := []models.Launch{}
+	for rows.Next() {
+		var launch models.Launch
+		var startedAt string
+		var endedAt sql.NullString
+		var duration sql.NullInt64
+		if err := rows.Scan(&launch.ID, &launch.AccountID, &launch.UserID, &launch.UserEmail, &startedAt, &endedAt, &duration, &launch.Outcome, &launch.Error); err != nil {
+			return nil, fmt.Errorf("scan launch: %w", err)
+		}
+		launch.StartedAt = parseSQLiteTime(startedAt)
+		if endedAt.Valid {
+			t := parseSQLiteTime(endedAt.String)
+			launch.EndedAt = &t
+		}
+		if duration.Valid {
+			launch.DurationSeconds = &duration.Int64
+		}
+		launches = append(launches, launch)
+	}
+	return launches, rows.Err()
+}
+
+// recordFailedLaunch stores a launch that did not start Chrome. It only
+// logs its own errors so the launch error reaches the caller.
+func recordFailedLaunch(ctx context.Context, db *sql.DB, accountID, userID int64, launchErr error) {
+	if _, err := db.ExecContext(
+		ctx,
+		`INSERT INTO launches (account_id, user_id, started_at, ended_at, outcome, error) VALUES ($1, $2, $3, $3, $4, $5);`,
+		accountID,
+		userID,
+		time.Now().UTC().Format(time.RFC3339Nano),
+		models.LaunchFailed,
+		launchErr.Error(),
+	); err != nil {
+		log.Printf("record failed launch for account %d: %v", accountID, err)
+	}
+}
+
+// finishLaunch waits for the browser of a running launch to exit and stores
+// when it did.
+func finishLaunch(db *sql.DB, launchID int64, done <-chan error) {
+	exitErr := <-done
+	message := ""
+	if exitErr != nil {
+		message = exitErr.Error()
+	}
+	if _, err := db.ExecContext(
+		context.Background(),
+		`UPDATE launches SET ended_at = $1, outcome = $2, error = $3 WHERE id = $4;`,
+		time.Now().UTC().Format(time.RFC3339Nano),
+		models.LaunchExited,
+		message,
+		launchID,
+	); err != nil {
+		log.Printf("finish launch %d: %v", launchID, err)
+	}
+}
+
+// CloseInterruptedLaunches marks launches still running from a previous
+// server process: their browsers are no longer tracked, so their duration
+// stays unknown.
+func CloseInterruptedLaunches(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(
+		ctx,
+		`UPDATE launches SET outcome = $1 WHERE outcome = $2;`,
+		models.LaunchInterrupted,
+		models.LaunchRunning,
+	); err != nil {
+		return fmt.Errorf("close interrupted launches: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,305 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"netflix_central/models"
+)
+
+var ErrInvalidLibraryTab = errors.New("invalid library tab")
+
+const libraryTabSelect = `SELECT l.id, l.user_id, l.title, l.url,
+	(SELECT COUNT(*) FROM tabs t JOIN accounts a ON a.id = t.account_id WHERE t.library_tab_id = l.id AND a.deleted_at IS NULL),
+	l.created_at, l.updated_at
+	FROM library_tabs l`
+
+func ListLibraryTabs(ctx context.Context, db *sql.DB, userID int64) ([]models.LibraryTab, error) {
+	rows, err := db.QueryContext(ctx, libraryTabSelect+` WHERE l.user_id = $1 ORDER BY LOWER(l.title) ASC, l.id ASC;`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("query library tabs: %w", err)
+	}
+	defer rows.Close()
+
+	tabs := []models.LibraryTab{}
+	for rows.Next() {
+		tab, err := scanLibraryTab(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan library tab: %w", err)
+		}
+		tabs = append(tabs, tab)
+	}
+	return tabs, rows.Err()
+}
+
+func GetLibraryTab(ctx context.Context, q querier, id, userID int64) (models.LibraryTab, error) {
+	return scanLibraryTab(q.QueryRowContext(ctx, libraryTabSelect+` WHERE l.id = $1 AND l.user_id = $2;`, id, userID))
+}
+
+func CreateLibraryTab(ctx context.Context, db *sql.DB, userID int64, title, url string) (models.LibraryTab, error) {
+	title, url, err := normalizeTabInput(title, url)
+	if err != nil {
+		return models.LibraryTab{}, err
+	}
+
+	var id int64
+	err = withTx(ctx, db, func(tx *sql.Tx) error {
+		now := time.Now().UTC().Format(time.RFC3339Nano)
+		if err := tx.QueryRowContext(
+			ctx,
+			`INSERT INTO library_tabs (user_id, title, url, created_at, updated_at) VALUES ($1, $2, $3, $4, $4) RETURNING id;`,
+			userID,
+			title,
+			url,
+			now,
+		).Scan(&id); err != nil {
+			return fmt.Errorf("insert library tab: %w", err)
+		}
+		return recordAudit(ctx, tx, "library_tab.create", "library_tab", id, nil, map[string]any{"title": title, "url": url})
+	})
+	if err != nil {
+		return models.LibraryTab{}, err
+	}
+	return GetLibraryTab(ctx, db, id, userID)
+}
+
+// UpdateLibraryTab changes the library entry, and with it every linked tab
+// that does not override the changed value. Linked tabs get a new version.
+func UpdateLibraryTab(ctx context.Context, db *sql.DB, id, userID int64, title, url string) (models.LibraryTab, error) {
+	title, url, err := normalizeTabInput(title, url)
+	if err != nil {
+		return models.LibraryTab{}, err
+	}
+
+	err = withTx(ctx, db, func(tx *sql.Tx) error {
+		before, err := lockLibraryTab(ctx, tx, id, userID)
+		if err != nil {
+			return err
+		}
+		if err := checkLinkedTabVariables(ctx, tx, id, userID, url); err != nil {
+			return err
+		}
+
+		now := time.Now().UTC().Format(time.RFC3339Nano)
+		if _, err := tx.ExecContext(
+			ctx,
+			`UPDATE library_tabs SET title = $1, url = $2, updated_at = $3 WHERE id = $4;`,
+			title,
+			url,
+			now,
+			id,
+		); err != nil {
+			return fmt.Errorf("update library tab: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE tabs SET version = version + 1 WHERE library_tab_id = $1;`, id); err != nil {
+			return fmt.Errorf("bump linked tab versions: %w", err)
+		}
+		return recordAudit(ctx, tx, "library_tab.update", "library_tab", id,
+			map[string]any{"title": before.Title, "url": before.URL},
+			map[string]any{"title": title, "url": url})
+	})
+	if err != nil {
+		return models.LibraryTab{}, err
+	}
+	return GetLibraryTab(ctx, db, id, userID)
+}
+
+// DeleteLibraryTab removes the library entry. Linked tabs stay in their
+// accounts as ordinary tabs holding the values they showed before.
+func DeleteLibraryTab(ctx context.Context, db *sql.DB, id, userID int64) error {
+	return withTx(ctx, db, func(tx *sql.Tx) error {
+		before, err := lockLibraryTab(ctx, tx, id, userID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(
+			ctx,
+			`UPDATE tabs SET title = COALESCE(title, $1), url = COALESCE(url, $2), library_tab_id = NULL, version = version + 1
+			WHERE library_tab_id = $3;`,
+			before.Title,
+			before.URL,
+			id,
+		); err != nil {
+			return fmt.Errorf("detach linked tabs: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM library_tabs WHERE id = $1;`, id); err != nil {
+			return fmt.Errorf("delete library tab: %w", err)
+		}
+		return recordAudit(ctx, tx, "library_tab.delete", "library_tab", id, before, nil)
+	})
+}
+
+// LibraryTabUsages lists the accounts, outside the trash, that link the
+// library tab.
+func LibraryTabUsages(ctx context.Context, db *sql.DB, id, userID int64) ([]models.LibraryTabUsage, error) {
+	if _, err := GetLibraryTab(ctx, db, id, userID); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT a.id, a.label, a.netflix_email, t.id, t.title IS NOT NULL, t.url IS NOT NULL
+		FROM tabs t JOIN accounts a ON a.id = t.account_id
+		WHERE t.library_tab_id = $1 AND a.user_id = $2 AND a.deleted_at IS NULL
+		ORDER BY LOWER(a.label) ASC, a.id ASC, t.position ASC;`,
+		id,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query library tab usages: %w", err)
+	}
+	defer rows.Close()
+
+	usages := []models.LibraryTabUsage{}
+	for rows.Next() {
+		var u models.LibraryTabUsage
+		if err := rows.Scan(&u.AccountID, &u.AccountLabel, &u.NetflixEmail, &u.TabID, &u.TitleOverridden, &u.URLOverridden); err != nil {
+			return nil, fmt.Errorf("scan library tab usage: %w", err)
+		}
+		usages = append(usages, u)
+	}
+	return usages, rows.Err()
+}
+
+// AttachLibraryTab links the library tab to each account as its last tab.
+// Accounts that already link it are skipped. Like ApplyTabTemplate it runs
+// in one transaction, so an unknown account changes nothing.
+func AttachLibraryTab(ctx context.Context, db *sql.DB, id, userID int64, accountIDs []int64) ([]models.LibraryTabAttachResult, error) {
+	accountIDs = uniqueIDs(accountIDs)
+	if len(accountIDs) == 0 {
+		return nil, fmt.Errorf("%w: account_ids is required", ErrInvalidLibraryTab)
+	}
+	if len(accountIDs) > maxBulkAccounts {
+		return nil, fmt.Errorf("%w: at most %d accounts per request", ErrInvalidLibraryTab, maxBulkAccounts)
+	}
+
+	results := make([]models.LibraryTabAttachResult, 0, len(accountIDs))
+	err := withTxRetry(ctx, db, func(tx *sql.Tx) error {
+		results = results[:0]
+		lib, err := lockLibraryTab(ctx, tx, id, userID)
+		if err != nil {
+			return err
+		}
+
+		for _, accountID := range accountIDs {
+			if err := lockOwnedAccount(ctx, tx, accountID, userID); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return fmt.Errorf("%w: account %d not found", ErrInvalidLibraryTab, accountID)
+				}
+				return err
+			}
+			res := models.LibraryTabAttachResult{AccountID: accountID}
+			err := tx.QueryRowContext(ctx, `SELECT id FROM tabs WHERE account_id = $1 AND library_tab_id = $2 LIMIT 1;`, accountID, id).Scan(&res.TabID)
+			switch {
+			case err == nil:
+				res.Skipped = true
+				results = append(results, res)
+				continue
+			case !errors.Is(err, sql.ErrNoRows):
+				return fmt.Errorf("check linked tab: %w", err)
+			}
+			if err := checkTabURLVariables(ctx, tx, accountID, lib.URL); err != nil {
+				return fmt.Errorf("account %d: %w", accountID, err)
+			}
+
+			if err := tx.QueryRowContext(
+				ctx,
+				`INSERT INTO tabs (account_id, library_tab_id, position)
+				VALUES ($1, $2, (SELECT COALESCE(MAX(position), 0) + 1 FROM tabs WHERE account_id = $1))
+				RETURNING id;`,
+				accountID,
+				id,
+			).Scan(&res.TabID); err != nil {
+				return fmt.Errorf("insert linked tab: %w", err)
+			}
+			if err := recordAudit(ctx, tx, "library_tab.attach", "tab", res.TabID, nil, map[string]any{
+				"account_id":     accountID,
+				"library_tab_id": id,
+			}); err != nil {
+				return err
+			}
+			results = append(results, res)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// checkLinkedTabVariables rejects a new URL for the library tab when an
+// account linking it without its own URL, trashed ones included, does not
+// define one of its variables. It locks those accounts so their variables
+// cannot change before the update commits.
+func checkLinkedTabVariables(ctx context.Context, tx *sql.Tx, id, userID int64, link string) error {
+	if _, ok := undefinedTabVariable(link, nil); !ok {
+		return nil
+	}
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT a.id, a.label FROM accounts a
+		WHERE a.user_id = $1 AND a.id IN (SELECT account_id FROM tabs WHERE library_tab_id = $2 AND url IS NULL)
+		ORDER BY a.id ASC FOR UPDATE;`,
+		userID,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("lock linked accounts: %w", err)
+	}
+	type linkedAccount struct {
+		id    int64
+		label string
+	}
+	var accounts []linkedAccount
+	for rows.Next() {
+		var acc linkedAccount
+		if err := rows.Scan(&acc.id, &acc.label); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan linked account: %w", err)
+		}
+		accounts = append(accounts, acc)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, acc := range accounts {
+		vars, err := GetAccountVariables(ctx, tx, acc.id)
+		if err != nil {
+			return err
+		}
+		if name, ok := undefinedTabVariable(link, vars); ok {
+			return fmt.Errorf("%w: account %q links this tab but does not define {{%s}}", ErrInvalidLibraryTab, acc.label, name)
+		}
+	}
+	return nil
+}
+
+func lockLibraryTab(ctx context.Context, tx *sql.Tx, id, userID int64) (models.LibraryTab, error) {
+	var tab models.LibraryTab
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT id, user_id, title, url FROM library_tabs WHERE id = $1 AND user_id = $2 FOR UPDATE;`,
+		id,
+		userID,
+	).Scan(&tab.ID, &tab.UserID, &tab.Title, &tab.URL)
+	return tab, err
+}
+
+func scanLibraryTab(row rowScanner) (models.LibraryTab, error) {
+	var tab models.LibraryTab
+	var createdAt, updatedAt string
+	if err := row.Scan(&tab.ID, &tab.UserID, &tab.Title, &tab.URL, &tab.UsageCount, &createdAt, &updatedAt); err != nil {
+		return models.LibraryTab{}, err
+	}
+	tab.CreatedAt = parseSQLiteTime(createdAt)
+	tab.UpdatedAt = parseSQLiteTime(updatedAt)
+	return tab, nil
+}
@@ -0,0 +1,32 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestLibraryTabVariablesMustBeDefinedByLinkedAccounts(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	userID := createTestUser(t, db)
+	acc := createTestAccount(t, db, userID)
+
+	lib, err := CreateLibraryTab(ctx, db, userID, "Profile", "https://www.netflix.com/{{profile}}")
+	if err != nil {
+		t.Fatalf("CreateLibraryTab: %v", err)
+	}
+	if _, err := AttachLibraryTab(ctx, db, lib.ID, userID, []int64{acc.ID}); !errors.Is(err, ErrInvalidTab) {
+		t.Fatalf("attaching to an account without the variable = %v, want %v", err, ErrInvalidTab)
+	}
+
+	if _, err := SetAccountVariables(ctx, db, acc.ID, userID, map[string]string{"profile": "kids"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AttachLibraryTab(ctx, db, lib.ID, userID, []int64{acc.ID}); err != nil {
+		t.Fatalf("attaching to an account with the variable: %v", err)
+	}
+	if _, err := UpdateLibraryTab(ctx, db, lib.ID, userID, "Profile", "https://www.netflix.com/{{profile}}?pin={{pin}}"); !errors.Is(err, ErrInvalidLibraryTab) {
+		t.Fatalf("updating to a variable a linked account lacks = %v, want %v", err, ErrInvalidLibraryTab)
+	}
+}
@@ -0,0 +1,319 @@
+package services
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrWeakPassword = errors.New("password does not meet policy")
+
+const (
+	schemeBcrypt   = "bcrypt"
+	schemeArgon2id = "argon2id"
+)
+
+// maxPasswordBytes is the longest password bcrypt accepts. It applies to
+// every scheme so a hash can always be moved to bcrypt later.
+const maxPasswordBytes = 72
+
+// PasswordPolicy describes the rules a new password must satisfy.
+type PasswordPolicy struct {
+	MinLength     int
+	DisallowEmail bool
+
+	breached    *breachedList
+	breachedErr error
+}
+
+// hashConfig describes how new password hashes are produced.
+type hashConfig struct {
+	Scheme     string
+	BcryptCost int
+	ArgonTime  uint32
+	ArgonMem   uint32
+	ArgonPar   uint8
+}
+
+// LoadPasswordPolicy reads the password policy from environment variables.
+func LoadPasswordPolicy() PasswordPolicy {
+	breached, err := loadBreachedPasswords()
+	return PasswordPolicy{
+		MinLength:     envInt("PASSWORD_MIN_LENGTH", 8),
+		DisallowEmail: os.Getenv("PASSWORD_ALLOW_EMAIL") != "true",
+		breached:      breached,
+		breachedErr:   err,
+	}
+}
+
+// Validate checks the password against the policy for the given email.
+func (p PasswordPolicy) Validate(email, password string) error {
+	if len([]rune(password)) < p.MinLength {
+		return fmt.Errorf("%w: password must be at least %d characters", ErrWeakPassword, p.MinLength)
+	}
+	if len(password) > maxPasswordBytes {
+		return fmt.Errorf("%w: password must be at most %d bytes", ErrWeakPassword, maxPasswordBytes)
+	}
+
+	if p.DisallowEmail {
+		lowered := strings.ToLower(strings.TrimSpace(password))
+		email = strings.ToLower(strings.TrimSpace(email))
+		local := strings.Split(email, "@")[0]
+		if lowered == email || (local != "" && lowered == local) {
+			return fmt.Errorf("%w: password must not be your email", ErrWeakPassword)
+		}
+	}
+
+	if p.breachedErr != nil {
+		return fmt.Errorf("check breached passwords: %w", p.breachedErr)
+	}
+	if p.breached != nil {
+		breached, err := p.breached.contains(password)
+		if err != nil {
+			return fmt.Errorf("check breached passwords: %w", err)
+		}
+		if breached {
+			return fmt.Errorf("%w: password appears in a known breach", ErrWeakPassword)
+		}
+	}
+
+	return nil
+}
+
+var (
+	breachedOnce sync.Once
+	breached     *breachedList
+	breachedErr  error
+)
+
+// LoadBreachedPasswords indexes the PASSWORD_BREACHED_LIST file, if set. The
+// server calls it at startup, so a missing or malformed list stops it with a
+// configuration error instead of failing every registration.
+func LoadBreachedPasswords() error {
+	_, err := loadBreachedPasswords()
+	return err
+}
+
+func loadBreachedPasswords() (*breachedList, error) {
+	breachedOnce.Do(func() {
+		path := strings.TrimSpace(os.Getenv("PASSWORD_BREACHED_LIST"))
+		if path == "" {
+			return
+		}
+		breached, breachedErr = openBreachedList(path)
+		if breachedErr != nil {
+			breachedErr = fmt.Errorf("PASSWORD_BREACHED_LIST: %w", breachedErr)
+		}
+	})
+	return breached, breachedErr
+}
+
+// breachedPrefixes is the number of 5-hex-digit SHA-1 prefixes.
+const breachedPrefixes = 1 << 20
+
+// breachedList is a file of SHA-1 hashes sorted by hash, one "HASH" or
+// "HASH:COUNT" per line, as in the Pwned Passwords dumps ordered by hash.
+// offsets[p] is where the lines with prefix p start, so a lookup reads only
+// the lines sharing the password's 5-character prefix, mirroring the
+// k-anonymity range lookup, and the list never has to fit in memory.
+type breachedList struct {
+	file    *os.File
+	offsets []int64
+}
+
+func openBreachedList(path string) (*breachedList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	list := &breachedList{file: file, offsets: make([]int64, breachedPrefixes+1)}
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	next := 0
+	for lineNo := 1; ; lineNo++ {
+		line, err := reader.ReadString('\n')
+		start := offset
+		offset += int64(len(line))
+		if hash, _, _ := strings.Cut(strings.TrimSpace(line), ":"); hash != "" {
+			prefix, perr := strconv.ParseUint(hash[:min(5, len(hash))], 16, 32)
+			if len(hash) != sha1.Size*2 || perr != nil {
+				file.Close()
+				return nil, fmt.Errorf("line %d is not a SHA-1 hash", lineNo)
+			}
+			if int(prefix) < next-1 {
+				file.Close()
+				return nil, fmt.Errorf("line %d: the list must be sorted by hash", lineNo)
+			}
+			for ; next <= int(prefix); next++ {
+				list.offsets[next] = start
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	for ; next <= breachedPrefixes; next++ {
+		list.offsets[next] = offset
+	}
+	return list, nil
+}
+
+func (l *breachedList) contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, _ := strconv.ParseUint(digest[:5], 16, 32)
+
+	start, end := l.offsets[prefix], l.offsets[prefix+1]
+	buf := make([]byte, end-start)
+	if _, err := l.file.ReadAt(buf, start); err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	for _, line := range strings.Split(string(buf), "\n") {
+		hash, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+		if strings.EqualFold(hash, digest) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func loadHashConfig() hashConfig {
+	scheme := strings.ToLower(strings.TrimSpace(os.Getenv("PASSWORD_HASH_SCHEME")))
+	if scheme != schemeArgon2id {
+		scheme = schemeBcrypt
+	}
+
+	// Out of range values fall back to the defaults rather than wrapping
+	// around in the conversions: argon2 panics with zero threads.
+	return hashConfig{
+		Scheme:     scheme,
+		BcryptCost: envIntInRange("PASSWORD_BCRYPT_COST", bcrypt.DefaultCost, bcrypt.MinCost, bcrypt.MaxCost),
+		ArgonTime:  uint32(envIntInRange("PASSWORD_ARGON2_TIME", 1, 1, math.MaxInt32)),
+		ArgonMem:   uint32(envIntInRange("PASSWORD_ARGON2_MEMORY_KB", 64*1024, 8, math.MaxInt32)),
+		ArgonPar:   uint8(envIntInRange("PASSWORD_ARGON2_THREADS", 4, 1, math.MaxUint8)),
+	}
+}
+
+// hashPassword produces an encoded hash using the configured scheme.
+func hashPassword(password string) (string, error) {
+	cfg := loadHashConfig()
+	if cfg.Scheme == schemeArgon2id {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, cfg.ArgonTime, cfg.ArgonMem, cfg.ArgonPar, 32)
+		return fmt.Sprintf(
+			"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version,
+			cfg.ArgonMem,
+			cfg.ArgonTime,
+			cfg.ArgonPar,
+			base64.RawStdEncoding.EncodeToString(salt),
+			base64.RawStdEncoding.EncodeToString(key),
+		), nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cfg.BcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// verifyPassword compares the password with an encoded hash. needsRehash
+// reports whether the stored hash is weaker than the current configuration.
+func verifyPassword(encoded, password string) (ok bool, needsRehash bool, err error) {
+	cfg := loadHashConfig()
+
+	if strings.HasPrefix(encoded, "$argon2id$") {
+		var version int
+		var mem, iterations uint32
+		var par uint8
+		parts := strings.Split(encoded, "$")
+		if len(parts) != 6 {
+			return false, false, errors.New("malformed argon2id hash")
+		}
+		if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+			return false, false, fmt.Errorf("parse argon2id version: %w", err)
+		}
+		if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &mem, &iterations, &par); err != nil {
+			return false, false, fmt.Errorf("parse argon2id params: %w", err)
+		}
+		if iterations == 0 || par == 0 {
+			return false, false, errors.New("malformed argon2id hash")
+		}
+		salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+		if err != nil {
+			return false, false, fmt.Errorf("decode argon2id salt: %w", err)
+		}
+		want, err := base64.RawStdEncoding.DecodeString(parts[5])
+		if err != nil {
+			return false, false, fmt.Errorf("decode argon2id key: %w", err)
+		}
+
+		got := argon2.IDKey([]byte(password), salt, iterations, mem, par, uint32(len(want)))
+		if subtle.ConstantTimeCompare(got, want) != 1 {
+			return false, false, nil
+		}
+		needsRehash = cfg.Scheme != schemeArgon2id ||
+			version != argon2.Version ||
+			mem < cfg.ArgonMem ||
+			iterations < cfg.ArgonTime ||
+			par < cfg.ArgonPar
+		return true, needsRehash, nil
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)); err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		return false, false, err
+	}
+	cost, err := bcrypt.Cost([]byte(encoded))
+	if err != nil {
+		return true, false, nil
+	}
+	needsRehash = cfg.Scheme != schemeBcrypt || cost < cfg.BcryptCost
+	return true, needsRehash, nil
+}
+
+func envInt(key string, fallback int) int {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return fallback
+	}
+	return n
+}
+
+// envIntInRange is envInt limited to lo..hi; values outside the range fall
+// back too.
+func envIntInRange(key string, fallback, lo, hi int) int {
+	if n := envInt(key, fallback); n >= lo && n <= hi {
+		return n
+	}
+	return fallback
+}
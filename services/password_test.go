@@ -0,0 +1,85 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadHashConfigFallsBackForOutOfRangeValues(t *testing.T) {
+	cases := []struct {
+		threads string
+		want    uint8
+	}{
+		{"", 4},
+		{"1", 1},
+		{"255", 255},
+		{"256", 4},
+		{"0", 4},
+		{"many", 4},
+	}
+	for _, tc := range cases {
+		t.Setenv("PASSWORD_ARGON2_THREADS", tc.threads)
+		if got := loadHashConfig().ArgonPar; got != tc.want {
+			t.Errorf("PASSWORD_ARGON2_THREADS=%q: threads = %d, want %d", tc.threads, got, tc.want)
+		}
+	}
+
+	t.Setenv("PASSWORD_HASH_SCHEME", schemeArgon2id)
+	t.Setenv("PASSWORD_ARGON2_THREADS", "256")
+	t.Setenv("PASSWORD_ARGON2_MEMORY_KB", "64")
+	hash, err := hashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _, err := verifyPassword(hash, "correct horse"); !ok || err != nil {
+		t.Fatalf("verifyPassword = %v, %v; want true", ok, err)
+	}
+}
+
+func TestBreachedList(t *testing.T) {
+	// SHA-1 of "password" and "123456", with a neighbour sharing the prefix.
+	lines := []string{
+		"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD7:3",
+		"5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8:9545824",
+		"7C4A8D09CA3762AF61E59520943DC26494F8941B:37359195",
+	}
+	list := writeBreachedList(t, strings.Join(lines, "\r\n")+"\r\n")
+	for password, want := range map[string]bool{"password": true, "123456": true, "password1x": false} {
+		got, err := list.contains(password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("contains(%q) = %v, want %v", password, got, want)
+		}
+	}
+
+	for name, content := range map[string]string{
+		"unsorted":  lines[2] + "\n" + lines[0] + "\n",
+		"not sha-1": "5BAA61E4C9B93F3F\n",
+	} {
+		path := filepath.Join(t.TempDir(), "breached.txt")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := openBreachedList(path); err == nil {
+			t.Errorf("%s list: openBreachedList succeeded, want an error", name)
+		}
+	}
+}
+
+func writeBreachedList(t *testing.T, content string) *breachedList {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	list, err := openBreachedList(path)
+	if err != nil {
+		t.Fatalf("openBreachedList: %v", err)
+	}
+	t.Cleanup(func() { list.file.Close() })
+	return list
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"netflix_central/models"
+)
+
+var (
+	ErrInvalidProfile  = errors.New("invalid profile")
+	ErrProfileCapacity = errors.New("profile capacity reached")
+)
+
+// MaturityLevels lists the accepted profile maturity settings.
+var MaturityLevels = []string{"kids", "older_kids", "teens", "all"}
+
+const defaultProfileCapacity = 5
+
+// planProfileCapacity is the maximum number of household profiles per plan.
+// Netflix currently allows five on every tier; accounts without a
+// subscription fall back to defaultProfileCapacity.
+var planProfileCapacity = map[string]int{
+	"mobile":            5,
+	"basic":             5,
+	"standard_with_ads": 5,
+	"standard":          5,
+	"premium":           5,
+}
+
+const profileColumns = `id, account_id, name, assigned_client, pin_protected, maturity_level, notes, created_at`
+
+// ProfileInput carries the editable profile fields.
+type ProfileInput struct {
+	Name           string
+	AssignedClient string
+	PinProtected   bool
+	MaturityLevel  string
+	Notes          string
+}
+
+// GetAccountDetail returns the account with its household profiles.
+func GetAccountDetail(ctx context.Context, db *sql.DB, id, userID int64) (models.Account, error) {
+	acc, err := GetAccount(ctx, db, id, userID)
+	if err != nil {
+		return acc, err
+	}
+	profiles, err := ListProfiles(ctx, db, id)
+	if err != nil {
+		return acc, err
+	}
+	acc.Profiles = profiles
+	return acc, nil
+}
+
+func ListProfiles(ctx context.Context, db *sql.DB, accountID int64) ([]models.NetflixProfile, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+profileColumns+` FROM netflix_profiles WHERE account_id = $1 ORDER BY id ASC;`, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("query profiles: %w", err)
+	}
+	defer rows.Close()
+
+	profiles := []models.NetflixProfile{}
+	for rows.Next() {
+		profile, err := scanProfile(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan profile: %w", err)
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles, rows.Err()
+}
+
+func GetProfile(ctx context.Context, db *sql.DB, id, accountID int64) (models.NetflixProfile, error) {
+	return scanProfile(db.QueryRowContext(ctx, `SELECT `+profileColumns+` FROM netflix_profiles WHERE id = $1 AND account_id = $2;`, id, accountID))
+}
+
+// CreateProfile adds a profile, refusing once the account's plan capacity is reached.
+func CreateProfile(ctx context.Context, db *sql.DB, accountID, userID int64, in ProfileInput) (models.NetflixProfile, error) {
+	in, err := normalizeProfile(in)
+	if err != nil {
+		return models.NetflixProfile{}, err
+	}
+
+	createdAt := time.Now().UTC().Format(time.RFC3339Nano)
+	var id int64
+	err = withTx(ctx, db, func(tx *sql.Tx) error {
+		// Lock the account so concurrent creates cannot both pass the capacity check.
+		if err := lockOwnedAccount(ctx, tx, accountID, userID); err != nil {
+			return err
+		}
+
+		capacity, err := profileCapacity(ctx, tx, accountID)
+		if err != nil {
+			return err
+		}
+
+		var count int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM netflix_profiles WHERE account_id = $1;`, accountID).Scan(&count); err != nil {
+			return fmt.Errorf("count profiles: %w", err)
+		}
+		if count >= capacity {
+			return fmt.Errorf("%w: this plan allows %d profiles", ErrProfileCapacity, capacity)
+		}
+
+		if err := tx.QueryRowContext(
+			ctx,
+			`INSERT INTO netflix_profiles (account_id, name, assigned_client, pin_protected, maturity_level, notes, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id;`,
+			accountID,
+			in.Name,
+			in.AssignedClient,
+			in.PinProtected,
+			in.MaturityLevel,
+			in.Notes,
+			createdAt,
+		).Scan(&id); err != nil {
+			if isUniqueViolation(err) {
+				return fmt.Errorf("%w: a profile with this name already exists", ErrInvalidProfile)
+			}
+			return fmt.Errorf("insert profile: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.NetflixProfile{}, err
+	}
+
+	return models.NetflixProfile{
+		ID:             id,
+		AccountID:      accountID,
+		Name:           in.Name,
+		AssignedClient: in.AssignedClient,
+		PinProtected:   in.PinProtected,
+		MaturityLevel:  in.MaturityLevel,
+		Notes:          in.Notes,
+		CreatedAt:      parseSQLiteTime(createdAt),
+	}, nil
+}
+
+func UpdateProfile(ctx context.Context, db *sql.DB, id, accountID, userID int64, in ProfileInput) (models.NetflixProfile, error) {
+	in, err := normalizeProfile(in)
+	if err != nil {
+		return models.NetflixProfile{}, err
+	}
+
+	var profile models.NetflixProfile
+	err = withTx(ctx, db, func(tx *sql.Tx) error {
+		if err := lockOwnedAccount(ctx, tx, accountID, userID); err != nil {
+			return err
+		}
+
+		var err error
+		profile, err = scanProfile(tx.QueryRowContext(
+			ctx,
+			`UPDATE netflix_profiles SET name = $1, assigned_client = $2, pin_protected = $3, maturity_level = $4, notes = $5
+			WHERE id = $6 AND account_id = $7 RETURNING `+profileColumns+`;`,
+			in.Name,
+			in.AssignedClient,
+			in.PinProtected,
+			in.MaturityLevel,
+			in.Notes,
+			id,
+			accountID,
+		))
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%w: a profile with this name already exists", ErrInvalidProfile)
+		}
+		return err
+	})
+	return profile, err
+}
+
+func DeleteProfile(ctx context.Context, db *sql.DB, id, accountID, userID int64) error {
+	return withTx(ctx, db, func(tx *sql.Tx) error {
+		if err := lockOwnedAccount(ctx, tx, accountID, userID); err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, `DELETE FROM netflix_profiles WHERE id = $1 AND account_id = $2;`, id, accountID)
+		if err != nil {
+			return fmt.Errorf("delete profile: %w", err)
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+}
+
+func profileCapacity(ctx context.Context, tx *sql.Tx, accountID int64) (int, error) {
+	var plan string
+	err := tx.QueryRowContext(ctx, `SELECT plan FROM subscriptions WHERE account_id = $1;`, accountID).Scan(&plan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return defaultProfileCapacity, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("load plan: %w", err)
+	}
+	return capacityForPlan(plan), nil
+}
+
+// capacityForPlan returns how many profiles, and therefore rentable seats, a plan has.
+func capacityForPlan(plan string) int {
+	if capacity, ok := planProfileCapacity[plan]; ok {
+		return capacity
+	}
+	return defaultProfileCapacity
+}
+
+func normalizeProfile(in ProfileInput) (ProfileInput, error) {
+	in.Name = strings.TrimSpace(in.Name)
+	in.AssignedClient = strings.TrimSpace(in.AssignedClient)
+	in.Notes = strings.TrimSpace(in.Notes)
+	in.MaturityLevel = strings.ToLower(strings.TrimSpace(in.MaturityLevel))
+
+	if in.Name == "" {
+		return in, fmt.Errorf("%w: name is required", ErrInvalidProfile)
+	}
+	if in.MaturityLevel == "" {
+		in.MaturityLevel = "all"
+	}
+	for _, level := range MaturityLevels {
+		if level == in.MaturityLevel {
+			return in, nil
+		}
+	}
+	return in, fmt.Errorf("%w: maturity_level must be one of %s", ErrInvalidProfile, strings.Join(MaturityLevels, ", "))
+}
+
+func scanProfile(row rowScanner) (models.NetflixProfile, error) {
+	var profile models.NetflixProfile
+	var created string
+	if err := row.Scan(&profile.ID, &profile.AccountID, &profile.Name, &profile.AssignedClient, &profile.PinProtected, &profile.MaturityLevel, &profile.Notes, &created); err != nil {
+		return profile, err
+	}
+	profile.CreatedAt = parseSQLiteTime(created)
+	return profile, nil
+}
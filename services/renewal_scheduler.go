@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"netflix_central/models"
+)
+
+const reminderKindRenewal = "renewal_due"
+
+// RenewalConfig controls the background renewal check.
+type RenewalConfig struct {
+	Interval time.Duration
+	LeadDays int
+}
+
+// LoadRenewalConfig reads RENEWAL_CHECK_INTERVAL (a Go duration) and
+// RENEWAL_REMINDER_DAYS from the environment.
+func LoadRenewalConfig() RenewalConfig {
+	cfg := RenewalConfig{Interval: time.Hour, LeadDays: envInt("RENEWAL_REMINDER_DAYS", 3)}
+	if raw := strings.TrimSpace(os.Getenv("RENEWAL_CHECK_INTERVAL")); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+			cfg.Interval = d
+		}
+	}
+	return cfg
+}
+
+// RunRenewalScheduler checks renewals once immediately and then on every
+// tick until ctx is cancelled.
+func RunRenewalScheduler(ctx context.Context, db *sql.DB, cfg RenewalConfig) {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := CheckRenewals(ctx, db, cfg.LeadDays, time.Now().UTC()); err != nil {
+			log.Printf("renewal check: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckRenewals advances renewal dates that have passed, flags subscriptions
+// renewing within leadDays as due soon and raises one reminder per renewal.
+// The steps run in one transaction, so a failure leaves nothing half done.
+func CheckRenewals(ctx context.Context, db *sql.DB, leadDays int, now time.Time) error {
+	return withTx(ctx, db, func(tx *sql.Tx) error {
+		return checkRenewalsTx(ctx, tx, leadDays, now)
+	})
+}
+
+func checkRenewalsTx(ctx context.Context, tx *sql.Tx, leadDays int, now time.Time) error {
+	today := now.Format("2006-01-02")
+
+	if err := advancePastRenewals(ctx, tx, now); err != nil {
+		return err
+	}
+
+	// Only rows whose flag changes are written, not every row on every tick.
+	if _, err := tx.ExecContext(
+		ctx,
+		`UPDATE subscriptions SET due_soon = (next_renewal IS NOT NULL AND next_renewal >= $1::date AND next_renewal <= $1::date + $2::int)
+		WHERE due_soon IS DISTINCT FROM (next_renewal IS NOT NULL AND next_renewal >= $1::date AND next_renewal <= $1::date + $2::int);`,
+		today,
+		leadDays,
+	); err != nil {
+		return fmt.Errorf("flag due subscriptions: %w", err)
+	}
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`WITH due AS (
+			UPDATE subscriptions s SET reminded_for = s.next_renewal
+			FROM accounts a
+			WHERE a.id = s.account_id AND a.deleted_at IS NULL AND s.due_soon AND s.reminded_for IS DISTINCT FROM s.next_renewal
+			RETURNING s.account_id, a.user_id, a.label, s.next_renewal
+		)
+		INSERT INTO reminders (user_id, account_id, kind, due_date, message, created_at)
+		SELECT user_id, account_id, $1, next_renewal, 'Renewal for ' || label || ' is due on ' || to_char(next_renewal, 'YYYY-MM-DD'), $2
+		FROM due
+		RETURNING account_id, message;`,
+		reminderKindRenewal,
+		now.Format(time.RFC3339Nano),
+	)
+	if err != nil {
+		return fmt.Errorf("emit renewal reminders: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var accountID int64
+		var message string
+		if err := rows.Scan(&accountID, &message); err != nil {
+			return fmt.Errorf("scan reminder: %w", err)
+		}
+		log.Printf("reminder for account %d: %s", accountID, message)
+	}
+	return rows.Err()
+}
+
+// advancePastRenewals moves renewal dates that are already behind us to the
+// next billing date, for subscriptions with a billing day.
+func advancePastRenewals(ctx context.Context, tx *sql.Tx, now time.Time) error {
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT account_id, billing_day FROM subscriptions WHERE billing_day IS NOT NULL AND next_renewal < $1::date;`,
+		now.Format("2006-01-02"),
+	)
+	if err != nil {
+		return fmt.Errorf("query past renewals: %w", err)
+	}
+
+	type pastRenewal struct {
+		accountID int64
+		day       int
+	}
+	var past []pastRenewal
+	for rows.Next() {
+		var p pastRenewal
+		if err := rows.Scan(&p.accountID, &p.day); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan past renewal: %w", err)
+		}
+		past = append(past, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, p := range past {
+		next := nextBillingDate(p.day, now)
+		if _, err := tx.ExecContext(
+			ctx,
+			`UPDATE subscriptions SET next_renewal = $1, due_soon = FALSE WHERE account_id = $2;`,
+			next.Format("2006-01-02"),
+			p.accountID,
+		); err != nil {
+			return fmt.Errorf("advance renewal for account %d: %w", p.accountID, err)
+		}
+	}
+	return nil
+}
+
+// ListReminders returns the user's reminders, newest first. Dismissed ones are
+// included only when requested.
+func ListReminders(ctx context.Context, db *sql.DB, userID int64, includeDismissed bool) ([]models.Reminder, error) {
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT id, account_id, kind, due_date, message, created_at, dismissed_at FROM reminders
+		WHERE user_id = $1 AND ($2 OR dismissed_at IS NULL)
+			AND account_id IN (SELECT id FROM accounts WHERE user_id = $1 AND deleted_at IS NULL)
+		ORDER BY created_at DESC, id DESC;`,
+		userID,
+		includeDismissed,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query reminders: %w", err)
+	}
+	defer rows.Close()
+
+	reminders := []models.Reminder{}
+	for rows.Next() {
+		var r models.Reminder
+		var due, created string
+		var dismissed sql.NullString
+		if err := rows.Scan(&r.ID, &r.AccountID, &r.Kind, &due, &r.Message, &created, &dismissed); err != nil {
+			return nil, fmt.Errorf("scan reminder: %w", err)
+		}
+		r.DueDate = parseSQLiteTime(due)
+		r.CreatedAt = parseSQLiteTime(created)
+		if dismissed.Valid {
+			t := parseSQLiteTime(dismissed.String)
+			r.DismissedAt = &t
+		}
+		reminders = append(reminders, r)
+	}
+	return reminders, rows.Err()
+}
+
+func DismissReminder(ctx context.Context, db *sql.DB, id, userID int64) error {
+	result, err := db.ExecContext(
+		ctx,
+		`UPDATE reminders SET dismissed_at = $1 WHERE id = $2 AND user_id = $3 AND dismissed_at IS NULL;`,
+		time.Now().UTC().Format(time.RFC3339Nano),
+		id,
+		userID,
+	)
+	if err != nil {
+		return fmt.Errorf("dismiss reminder: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"netflix_central/models"
+)
+
+var (
+	ErrInvalidSeat = errors.New("invalid seat assignment")
+	ErrNoFreeSeat  = errors.New("no free seat")
+)
+
+const seatSelect = `SELECT s.id, s.customer_id, c.name, s.account_id, a.label, s.profile_id, COALESCE(p.name, ''), s.starts_on, s.ends_on, s.notes, s.created_at
+	FROM seat_assignments s
+	JOIN customers c ON c.id = s.customer_id
+	JOIN accounts a ON a.id = s.account_id
+	LEFT JOIN netflix_profiles p ON p.id = s.profile_id`
+
+// SeatInput carries the editable seat assignment fields. Dates are YYYY-MM-DD;
+// an empty EndsOn leaves the assignment open-ended.
+type SeatInput struct {
+	CustomerID int64
+	AccountID  int64
+	ProfileID  *int64
+	StartsOn   string
+	EndsOn     string
+	Notes      string
+}
+
+// ListCustomerSeats returns every seat assignment of a customer, newest first.
+func ListCustomerSeats(ctx context.Context, db *sql.DB, customerID, userID int64) ([]models.SeatAssignment, error) {
+	return querySeats(ctx, db, seatSelect+` WHERE s.customer_id = $1 AND c.user_id = $2 AND a.deleted_at IS NULL ORDER BY s.starts_on DESC, s.id DESC;`, customerID, userID)
+}
+
+// ListAccountOccupants returns the assignments of an account active on the given day.
+func ListAccountOccupants(ctx context.Context, db *sql.DB, accountID, userID int64, on time.Time) ([]models.SeatAssignment, error) {
+	return querySeats(
+		ctx,
+		db,
+		seatSelect+` WHERE s.account_id = $1 AND a.user_id = $2 AND a.deleted_at IS NULL AND s.starts_on <= $3::date AND (s.ends_on IS NULL OR s.ends_on >= $3::date)
+		ORDER BY s.starts_on ASC, s.id ASC;`,
+		accountID,
+		userID,
+		on.Format("2006-01-02"),
+	)
+}
+
+func GetSeat(ctx context.Context, db *sql.DB, id, userID int64) (models.SeatAssignment, error) {
+	seats, err := querySeats(ctx, db, seatSelect+` WHERE s.id = $1 AND a.user_id = $2 AND a.deleted_at IS NULL;`, id, userID)
+	if err != nil {
+		return models.SeatAssignment{}, err
+	}
+	if len(seats) == 0 {
+		return models.SeatAssignment{}, sql.ErrNoRows
+	}
+	return seats[0], nil
+}
+
+// CreateSeat assigns a customer to an account. The account must have a free
+// seat for the whole period and the profile, if any, must not be taken.
+func CreateSeat(ctx context.Context, db *sql.DB, userID int64, in SeatInput) (models.SeatAssignment, error) {
+	var id int64
+	err := saveSeat(ctx, db, userID, 0, in, func(tx *sql.Tx, startsOn string, endsOn any) error {
+		return tx.QueryRowContext(
+			ctx,
+			`INSERT INTO seat_assignments (customer_id, account_id, profile_id, starts_on, ends_on, notes, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id;`,
+			in.CustomerID,
+			in.AccountID,
+			in.ProfileID,
+			startsOn,
+			endsOn,
+			strings.TrimSpace(in.Notes),
+			time.Now().UTC().Format(time.RFC3339Nano),
+		).Scan(&id)
+	})
+	if err != nil {
+		return models.SeatAssignment{}, err
+	}
+	return GetSeat(ctx, db, id, userID)
+}
+
+func UpdateSeat(ctx context.Context, db *sql.DB, id, userID int64, in SeatInput) (models.SeatAssignment, error) {
+	if _, err := GetSeat(ctx, db, id, userID); err != nil {
+		return models.SeatAssignment{}, err
+	}
+
+	err := saveSeat(ctx, db, userID, id, in, func(tx *sql.Tx, startsOn string, endsOn any) error {
+		_, err := tx.ExecContext(
+			ctx,
+			`UPDATE seat_assignments SET customer_id = $1, account_id = $2, profile_id = $3, starts_on = $4, ends_on = $5, notes = $6 WHERE id = $7;`,
+			in.CustomerID,
+			in.AccountID,
+			in.ProfileID,
+			startsOn,
+			endsOn,
+			strings.TrimSpace(in.Notes),
+			id,
+		)
+		return err
+	})
+	if err != nil {
+		return models.SeatAssignment{}, err
+	}
+	return GetSeat(ctx, db, id, userID)
+}
+
+func DeleteSeat(ctx context.Context, db *sql.DB, id, userID int64) error {
+	result, err := db.ExecContext(
+		ctx,
+		`DELETE FROM seat_assignments s USING accounts a WHERE s.id = $1 AND a.id = s.account_id AND a.user_id = $2;`,
+		id,
+		userID,
+	)
+	if err != nil {
+		return fmt.Errorf("delete seat: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// FreeSeatReport lists, for every account of the user, how many seats are
+// occupied and free on the given day.
+func FreeSeatReport(ctx context.Context, db *sql.DB, userID int64, on time.Time) ([]models.FreeSeats, error) {
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT a.id, a.label, COALESCE(sub.plan, ''),
+			(SELECT COUNT(*) FROM seat_assignments s WHERE s.account_id = a.id AND s.starts_on <= $2::date AND (s.ends_on IS NULL OR s.ends_on >= $2::date))
+		FROM accounts a LEFT JOIN subscriptions sub ON sub.account_id = a.id
+		WHERE a.user_id = $1 AND a.deleted_at IS NULL
+		ORDER BY LOWER(a.label) ASC, a.id ASC;`,
+		userID,
+		on.Format("2006-01-02"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query free seats: %w", err)
+	}
+	defer rows.Close()
+
+	report := []models.FreeSeats{}
+	for rows.Next() {
+		var r models.FreeSeats
+		if err := rows.Scan(&r.AccountID, &r.AccountLabel, &r.Plan, &r.Occupied); err != nil {
+			return nil, fmt.Errorf("scan free seats: %w", err)
+		}
+		r.Capacity = capacityForPlan(r.Plan)
+		r.Free = r.Capacity - r.Occupied
+		if r.Free < 0 {
+			r.Free = 0
+		}
+		report = append(report, r)
+	}
+	return report, rows.Err()
+}
+
+// saveSeat validates the input and runs write inside a transaction holding a
+// lock on the target account, so capacity checks cannot race. excludeID is
+// the assignment being edited, or 0 when creating.
+func saveSeat(ctx context.Context, db *sql.DB, userID, excludeID int64, in SeatInput, write func(tx *sql.Tx, startsOn string, endsOn any) error) error {
+	startsOn, err := time.Parse("2006-01-02", strings.TrimSpace(in.StartsOn))
+	if err != nil {
+		return fmt.Errorf("%w: starts_on must be a YYYY-MM-DD date", ErrInvalidSeat)
+	}
+	var endsOn any
+	endsValue := "infinity"
+	if value := strings.TrimSpace(in.EndsOn); value != "" {
+		t, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return fmt.Errorf("%w: ends_on must be a YYYY-MM-DD date", ErrInvalidSeat)
+		}
+		if t.Before(startsOn) {
+			return fmt.Errorf("%w: ends_on must not be before starts_on", ErrInvalidSeat)
+		}
+		endsOn = t.Format("2006-01-02")
+		endsValue = t.Format("2006-01-02")
+	}
+	start := startsOn.Format("2006-01-02")
+
+	if _, err := GetCustomer(ctx, db, in.CustomerID, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: unknown customer", ErrInvalidSeat)
+		}
+		return err
+	}
+
+	return withTx(ctx, db, func(tx *sql.Tx) error {
+		var plan sql.NullString
+		if err := tx.QueryRowContext(
+			ctx,
+			`SELECT sub.plan FROM accounts a LEFT JOIN subscriptions sub ON sub.account_id = a.id WHERE a.id = $1 AND a.user_id = $2 AND a.deleted_at IS NULL FOR UPDATE OF a;`,
+			in.AccountID,
+			userID,
+		).Scan(&plan); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("%w: unknown account", ErrInvalidSeat)
+			}
+			return fmt.Errorf("lock account: %w", err)
+		}
+
+		overlap := `account_id = $1 AND id <> $2 AND starts_on <= $4::date AND (ends_on IS NULL OR ends_on >= $3::date)`
+
+		if in.ProfileID != nil {
+			var exists bool
+			if err := tx.QueryRowContext(
+				ctx,
+				`SELECT EXISTS (SELECT 1 FROM netflix_profiles WHERE id = $1 AND account_id = $2);`,
+				*in.ProfileID,
+				in.AccountID,
+			).Scan(&exists); err != nil {
+				return fmt.Errorf("check profile: %w", err)
+			}
+			if !exists {
+				return fmt.Errorf("%w: profile does not belong to the account", ErrInvalidSeat)
+			}
+
+			var taken bool
+			if err := tx.QueryRowContext(
+				ctx,
+				`SELECT EXISTS (SELECT 1 FROM seat_assignments WHERE `+overlap+` AND profile_id = $5);`,
+				in.AccountID,
+				excludeID,
+				start,
+				endsValue,
+				*in.ProfileID,
+			).Scan(&taken); err != nil {
+				return fmt.Errorf("check profile seat: %w", err)
+			}
+			if taken {
+				return fmt.Errorf("%w: the profile is already assigned in this period", ErrNoFreeSeat)
+			}
+		}
+
+		// Every assignment overlapping the period counts, which is conservative
+		// when several short assignments follow each other inside a long one.
+		var occupied int
+		if err := tx.QueryRowContext(
+			ctx,
+			`SELECT COUNT(*) FROM seat_assignments WHERE `+overlap+`;`,
+			in.AccountID,
+			excludeID,
+			start,
+			endsValue,
+		).Scan(&occupied); err != nil {
+			return fmt.Errorf("count seats: %w", err)
+		}
+		if capacity := capacityForPlan(plan.String); occupied >= capacity {
+			return fmt.Errorf("%w: all %d seats are taken in this period", ErrNoFreeSeat, capacity)
+		}
+
+		if err := write(tx, start, endsOn); err != nil {
+			return fmt.Errorf("save seat: %w", err)
+		}
+		return nil
+	})
+}
+
+func querySeats(ctx context.Context, db *sql.DB, query string, args ...any) ([]models.SeatAssignment, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query seats: %w", err)
+	}
+	defer rows.Close()
+
+	seats := []models.SeatAssignment{}
+	for rows.Next() {
+		var seat models.SeatAssignment
+		var profileID sql.NullInt64
+		var startsOn, created string
+		var endsOn sql.NullString
+		if err := rows.Scan(&seat.ID, &seat.CustomerID, &seat.CustomerName, &seat.AccountID, &seat.AccountLabel, &profileID, &seat.ProfileName, &startsOn, &endsOn, &seat.Notes, &created); err != nil {
+			return nil, fmt.Errorf("scan seat: %w", err)
+		}
+		if profileID.Valid {
+			seat.ProfileID = &profileID.Int64
+		}
+		seat.StartsOn = parseSQLiteTime(startsOn)
+		if endsOn.Valid {
+			t := parseSQLiteTime(endsOn.String)
+			seat.EndsOn = &t
+		}
+		seat.CreatedAt = parseSQLiteTime(created)
+		seats = append(seats, seat)
+	}
+	return seats, rows.Err()
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"netflix_central/models"
+)
+
+var (
+	ErrSessionLimit  = errors.New("too many sessions open")
+	ErrInvalidLaunch = errors.New("invalid launch request")
+)
+
+const maxLaunchBatch = 50
+
+// SessionConfig limits how many Chrome sessions run at once and how fast a
+// batch launch starts them.
+type SessionConfig struct {
+	MaxConcurrent int
+	Workers       int
+	Stagger       time.Duration
+}
+
+// LoadSessionConfig reads SESSION_MAX_CONCURRENT, SESSION_LAUNCH_WORKERS and
+// SESSION_LAUNCH_STAGGER (a Go duration) from the environment.
+func LoadSessionConfig() SessionConfig {
+	cfg := SessionConfig{
+		MaxConcurrent: envInt("SESSION_MAX_CONCURRENT", 8),
+		Workers:       envInt("SESSION_LAUNCH_WORKERS", 2),
+		Stagger:       2 * time.Second,
+	}
+	if raw := strings.TrimSpace(os.Getenv("SESSION_LAUNCH_STAGGER")); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil && d >= 0 {
+			cfg.Stagger = d
+		}
+	}
+	return cfg
+}
+
+// sessionRegistry tracks the Chrome processes started by this server, one
+// per account, until they exit.
+type sessionRegistry struct {
+	mu      sync.Mutex
+	running map[int64]*exec.Cmd
+}
+
+var runningSessions = &sessionRegistry{running: map[int64]*exec.Cmd{}}
+
+// launch starts Chrome for the account unless limit sessions are already
+// running. Opening an account whose browser is still running only opens a
+// new window in it, so it does not take another slot. For a new browser the
+// returned channel receives the result of its exit; it is nil when an
+// existing browser was reused. The warnings are those of startChrome.
+func (r *sessionRegistry) launch(account models.Account, tabs []models.Tab, groups []models.TabGroup, vars map[string]string, limit int) (<-chan error, []string, error) {
+	r.mu.Lock()
+	_, open := r.running[account.ID]
+	if !open {
+		if len(r.running) >= limit {
+			r.mu.Unlock()
+			return nil, nil, fmt.Errorf("%w: %d of %d sessions are running", ErrSessionLimit, limit, limit)
+		}
+		// Hold the slot while Chrome starts so concurrent launches see it.
+		r.running[account.ID] = nil
+	}
+	r.mu.Unlock()
+
+	cmd, warnings, err := startChrome(account, tabs, groups, vars)
+	if open {
+		if cmd != nil {
+			go cmd.Wait()
+		}
+		return nil, warnings, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		delete(r.running, account.ID)
+		return nil, nil, err
+	}
+	r.running[account.ID] = cmd
+	done := make(chan error, 1)
+	go r.wait(account.ID, cmd, done)
+	return done, warnings, nil
+}
+
+func (r *sessionRegistry) wait(accountID int64, cmd *exec.Cmd, done chan<- error) {
+	err := cmd.Wait()
+	if err != nil {
+		log.Printf("chrome session for account %d exited: %v", accountID, err)
+	}
+	r.mu.Lock()
+	if r.running[accountID] == cmd {
+		delete(r.running, accountID)
+	}
+	r.mu.Unlock()
+	done <- err
+	close(done)
+}
+
+func (r *sessionRegistry) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.running)
+}
+
+// LaunchSessions opens several accounts through a bounded worker pool.
+// Starts are spaced by the configured stagger across all workers, and each
+// account is reported separately; one failure does not stop the others.
+func LaunchSessions(ctx context.Context, db *sql.DB, userID int64, ids []int64) (models.SessionLaunchResult, error) {
+	cfg := LoadSessionConfig()
+	result := models.SessionLaunchResult{MaxConcurrent: cfg.MaxConcurrent, Items: []models.SessionLaunchItem{}}
+
+	ids = uniqueIDs(ids)
+	if len(ids) == 0 {
+		return result, fmt.Errorf("%w: account_ids is required", ErrInvalidLaunch)
+	}
+	if len(ids) > maxLaunchBatch {
+		return result, fmt.Errorf("%w: at most %d accounts per launch", ErrInvalidLaunch, maxLaunchBatch)
+	}
+
+	result.Total = len(ids)
+	result.Items = make([]models.SessionLaunchItem, len(ids))
+
+	var gate sync.Mutex
+	next := time.Now()
+	// waitTurn blocks until this worker may start the next launch.
+	waitTurn := func() error {
+		gate.Lock()
+		at := next
+		if now := time.Now(); at.Before(now) {
+			at = now
+		}
+		next = at.Add(cfg.Stagger)
+		gate.Unlock()
+
+		timer := time.NewTimer(time.Until(at))
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	workers := min(cfg.Workers, len(ids))
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				item := &result.Items[i]
+				item.AccountID = ids[i]
+
+				err := waitTurn()
+				if err == nil {
+					item.Warnings, err = OpenAccount(ctx, db, ids[i], userID, nil)
+				}
+				switch {
+				case err == nil:
+					startedAt := time.Now().UTC()
+					item.OK, item.StartedAt = true, &startedAt
+				case errors.Is(err, sql.ErrNoRows):
+					item.Error = "account not found"
+				default:
+					item.Error = err.Error()
+				}
+			}
+		}()
+	}
+	for i := range ids {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, item := range result.Items {
+		if item.OK {
+			result.Launched++
+		} else {
+			result.Failed++
+		}
+	}
+	result.Running = runningSessions.count()
+	return result, nil
+}
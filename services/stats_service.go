@@ -0,0 +1,309 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"netflix_central/models"
+)
+
+const (
+	defaultStatsDays = 7
+	maxStatsDays     = 90
+	maxStalestShown  = 10
+)
+
+// statsWindowStart is midnight, database time, of the first day of the
+// period; $2 is the number of days including today.
+const statsWindowStart = `date_trunc('day', NOW()) - make_interval(days => $2 - 1)`
+
+// StatsConfig controls how long computed stats are reused and when an
+// account counts as stale.
+type StatsConfig struct {
+	CacheTTL  time.Duration
+	StaleDays int
+}
+
+// LoadStatsConfig reads STATS_CACHE_TTL (a Go duration, 0 disables the
+// cache) and STATS_STALE_DAYS from the environment.
+func LoadStatsConfig() StatsConfig {
+	cfg := StatsConfig{CacheTTL: 30 * time.Second, StaleDays: envInt("STATS_STALE_DAYS", 30)}
+	if raw := strings.TrimSpace(os.Getenv("STATS_CACHE_TTL")); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil && d >= 0 {
+			cfg.CacheTTL = d
+		}
+	}
+	return cfg
+}
+
+type statsKey struct {
+	userID int64
+	days   int
+}
+
+type cachedStats struct {
+	stats   models.Stats
+	expires time.Time
+}
+
+var (
+	statsMu    sync.Mutex
+	statsCache = map[statsKey]cachedStats{}
+)
+
+// GetStats summarizes the user's accounts and the launches of the last days
+// days; 0 means the parameter was not given and selects defaultStatsDays.
+// Results are cached per user for STATS_CACHE_TTL, so they can lag behind by
+// that much.
+func GetStats(ctx context.Context, db *sql.DB, userID int64, days int) (models.Stats, error) {
+	if days == 0 {
+		days = defaultStatsDays
+	}
+	if days < 1 || days > maxStatsDays {
+		return models.Stats{}, fmt.Errorf("%w: days must be between 1 and %d", ErrInvalidQuery, maxStatsDays)
+	}
+
+	cfg := LoadStatsConfig()
+	key := statsKey{userID: userID, days: days}
+	now := time.Now()
+	statsMu.Lock()
+	cached, ok := statsCache[key]
+	statsMu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.stats, nil
+	}
+
+	stats, err := computeStats(ctx, db, userID, days, cfg.StaleDays)
+	if err != nil {
+		return models.Stats{}, err
+	}
+	if cfg.CacheTTL > 0 {
+		statsMu.Lock()
+		for k, entry := range statsCache {
+			if !now.Before(entry.expires) {
+				delete(statsCache, k)
+			}
+		}
+		statsCache[key] = cachedStats{stats: stats, expires: now.Add(cfg.CacheTTL)}
+		statsMu.Unlock()
+	}
+	return stats, nil
+}
+
+func computeStats(ctx context.Context, db *sql.DB, userID int64, days, staleDays int) (models.Stats, error) {
+	stats := models.Stats{GeneratedAt: time.Now().UTC(), Days: days}
+	var err error
+	if stats.Accounts, err = accountStats(ctx, db, userID, staleDays); err != nil {
+		return stats, err
+	}
+	if stats.Launches.PerDay, err = launchesPerDay(ctx, db, userID, days); err != nil {
+		return stats, err
+	}
+	for _, day := range stats.Launches.PerDay {
+		stats.Launches.Total += day.Launches
+	}
+	if stats.Operators, stats.Launches.AvgSessionSeconds, err = operatorUsage(ctx, db, userID, days); err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
+// accountStats counts the user's accounts by status and tag, and how many
+// were not opened in staleDays days.
+func accountStats(ctx context.Context, db *sql.DB, userID int64, staleDays int) (models.AccountStats, error) {
+	stats := models.AccountStats{ByStatus: map[string]int{}, ByTag: []models.TagCount{}, StaleDays: staleDays, StalestAccounts: []models.StaleAccount{}}
+
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT status, COUNT(*),
+			COUNT(*) FILTER (WHERE last_opened_at IS NULL OR last_opened_at < NOW() - make_interval(days => $2))
+		FROM accounts
+		WHERE user_id = $1 AND deleted_at IS NULL
+		GROUP BY status;`,
+		userID,
+		staleDays,
+	)
+	if err != nil {
+		return stats, fmt.Errorf("query account counts: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var count, stale int
+		if err := rows.Scan(&status, &count, &stale); err != nil {
+			return stats, fmt.Errorf("scan account count: %w", err)
+		}
+		stats.ByStatus[status] = count
+		stats.Total += count
+		stats.Stale += stale
+	}
+	if err := rows.Err(); err != nil {
+		return stats, err
+	}
+
+	tagRows, err := db.QueryContext(
+		ctx,
+		`SELECT t.id, t.name, t.color, COUNT(a.id)
+		FROM tags t
+		LEFT JOIN account_tags at ON at.tag_id = t.id
+		LEFT JOIN accounts a ON a.id = at.account_id AND a.deleted_at IS NULL
+		WHERE t.user_id = $1
+		GROUP BY t.id, t.name, t.color
+		ORDER BY COUNT(a.id) DESC, t.name, t.id;`,
+		userID,
+	)
+	if err != nil {
+		return stats, fmt.Errorf("query tag counts: %w", err)
+	}
+	defer tagRows.Close()
+	for tagRows.Next() {
+		var tc models.TagCount
+		if err := tagRows.Scan(&tc.TagID, &tc.Name, &tc.Color, &tc.Accounts); err != nil {
+			return stats, fmt.Errorf("scan tag count: %w", err)
+		}
+		stats.ByTag = append(stats.ByTag, tc)
+	}
+	if err := tagRows.Err(); err != nil {
+		return stats, err
+	}
+
+	staleRows, err := db.QueryContext(
+		ctx,
+		`SELECT id, label, status, last_opened_at
+		FROM accounts
+		WHERE user_id = $1 AND deleted_at IS NULL
+			AND (last_opened_at IS NULL OR last_opened_at < NOW() - make_interval(days => $2))
+		ORDER BY last_opened_at ASC NULLS FIRST, id
+		LIMIT $3;`,
+		userID,
+		staleDays,
+		maxStalestShown,
+	)
+	if err != nil {
+		return stats, fmt.Errorf("query stale accounts: %w", err)
+	}
+	defer staleRows.Close()
+	for staleRows.Next() {
+		var acc models.StaleAccount
+		var lastOpened sql.NullString
+		if err := staleRows.Scan(&acc.ID, &acc.Label, &acc.Status, &lastOpened); err != nil {
+			return stats, fmt.Errorf("scan stale account: %w", err)
+		}
+		if lastOpened.Valid {
+			t := parseSQLiteTime(lastOpened.String)
+			acc.LastOpenedAt = &t
+		}
+		stats.StalestAccounts = append(stats.StalestAccounts, acc)
+	}
+	return stats, staleRows.Err()
+}
+
+// launchesPerDay counts the launches of the user's accounts for every day of
+// the period, including days without any.
+func launchesPerDay(ctx context.Context, db *sql.DB, userID int64, days int) ([]models.DayCount, error) {
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT to_char(d.day, 'YYYY-MM-DD'), COUNT(l.id)
+		FROM generate_series(`+statsWindowStart+`, date_trunc('day', NOW()), interval '1 day') AS d(day)
+		LEFT JOIN launches l ON l.started_at >= d.day AND l.started_at < d.day + interval '1 day'
+			AND l.account_id IN (SELECT id FROM accounts WHERE user_id = $1 AND deleted_at IS NULL)
+		GROUP BY d.day
+		ORDER BY d.day;`,
+		userID,
+		days,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query launches per day: %w", err)
+	}
+	defer rows.Close()
+
+	perDay := make([]models.DayCount, 0, days)
+	for rows.Next() {
+		var day models.DayCount
+		if err := rows.Scan(&day.Date, &day.Launches); err != nil {
+			return nil, fmt.Errorf("scan launches per day: %w", err)
+		}
+		perDay = append(perDay, day)
+	}
+	return perDay, rows.Err()
+}
+
+// operatorUsage groups the period's launches by operator and account. It
+// also returns the average duration of all exited sessions, nil when none
+// exited.
+func operatorUsage(ctx context.Context, db *sql.DB, userID int64, days int) ([]models.OperatorUse, *float64, error) {
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT l.user_id, u.email, l.account_id, a.label, COUNT(*), MAX(l.started_at),
+			COALESCE(SUM(EXTRACT(EPOCH FROM l.ended_at - l.started_at)) FILTER (WHERE l.outcome = $3), 0)::DOUBLE PRECISION,
+			COUNT(*) FILTER (WHERE l.outcome = $3)
+		FROM launches l
+		JOIN accounts a ON a.id = l.account_id
+		JOIN users u ON u.id = l.user_id
+		WHERE a.user_id = $1 AND a.deleted_at IS NULL AND l.started_at >= `+statsWindowStart+`
+		GROUP BY l.user_id, u.email, l.account_id, a.label
+		ORDER BY l.user_id, COUNT(*) DESC, a.label, l.account_id;`,
+		userID,
+		days,
+		models.LaunchExited,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("query operator usage: %w", err)
+	}
+	defer rows.Close()
+
+	type totals struct {
+		seconds float64
+		exited  int
+	}
+	operators := []models.OperatorUse{}
+	var opTotals []totals
+	var all totals
+	for rows.Next() {
+		var opID int64
+		var email, lastStarted string
+		var use models.AccountUse
+		var seconds float64
+		var exited int
+		if err := rows.Scan(&opID, &email, &use.AccountID, &use.Label, &use.Launches, &lastStarted, &seconds, &exited); err != nil {
+			return nil, nil, fmt.Errorf("scan operator usage: %w", err)
+		}
+		use.LastLaunchedAt = parseSQLiteTime(lastStarted)
+
+		if n := len(operators); n == 0 || operators[n-1].UserID != opID {
+			operators = append(operators, models.OperatorUse{UserID: opID, Email: email, Accounts: []models.AccountUse{}})
+			opTotals = append(opTotals, totals{})
+		}
+		op := &operators[len(operators)-1]
+		op.Launches += use.Launches
+		op.Accounts = append(op.Accounts, use)
+		opTotals[len(opTotals)-1].seconds += seconds
+		opTotals[len(opTotals)-1].exited += exited
+		all.seconds += seconds
+		all.exited += exited
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	average := func(t totals) *float64 {
+		if t.exited == 0 {
+			return nil
+		}
+		avg := t.seconds / float64(t.exited)
+		return &avg
+	}
+	for i := range operators {
+		operators[i].AvgSessionSeconds = average(opTotals[i])
+	}
+	sort.SliceStable(operators, func(i, j int) bool {
+		return operators[i].Launches > operators[j].Launches
+	})
+	return operators, average(all), nil
+}
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"netflix_central/models"
+)
+
+var ErrInvalidSubscription = errors.New("invalid subscription")
+
+// Plans lists the known Netflix plan tiers.
+var Plans = []string{"mobile", "basic", "standard_with_ads", "standard", "premium"}
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+const subscriptionColumns = `account_id, plan, currency, amount, billing_day, next_renewal, payer, notes, due_soon, updated_at`
+
+// SubscriptionInput carries the editable subscription fields.
+type SubscriptionInput struct {
+	Plan        string
+	Currency    string
+	Amount      float64
+	BillingDay  *int
+	NextRenewal string
+	Payer       string
+	Notes       string
+}
+
+func GetSubscription(ctx context.Context, db *sql.DB, accountID int64) (models.Subscription, error) {
+	return scanSubscription(db.QueryRowContext(ctx, `SELECT `+subscriptionColumns+` FROM subscriptions WHERE account_id = $1;`, accountID))
+}
+
+// SaveSubscription creates or replaces the subscription of an account owned
+// by userID. When no next renewal date is given it is derived from the
+// billing day.
+func SaveSubscription(ctx context.Context, db *sql.DB, accountID, userID int64, in SubscriptionInput) (models.Subscription, error) {
+	plan := strings.ToLower(strings.TrimSpace(in.Plan))
+	if !isKnownPlan(plan) {
+		return models.Subscription{}, fmt.Errorf("%w: plan must be one of %s", ErrInvalidSubscription, strings.Join(Plans, ", "))
+	}
+
+	currency := strings.ToUpper(strings.TrimSpace(in.Currency))
+	if !currencyPattern.MatchString(currency) {
+		return models.Subscription{}, fmt.Errorf("%w: currency must be a 3-letter ISO code", ErrInvalidSubscription)
+	}
+
+	if in.Amount < 0 {
+		return models.Subscription{}, fmt.Errorf("%w: amount must not be negative", ErrInvalidSubscription)
+	}
+
+	if in.BillingDay != nil && (*in.BillingDay < 1 || *in.BillingDay > 31) {
+		return models.Subscription{}, fmt.Errorf("%w: billing_day must be between 1 and 31", ErrInvalidSubscription)
+	}
+
+	var nextRenewal *time.Time
+	if value := strings.TrimSpace(in.NextRenewal); value != "" {
+		t, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return models.Subscription{}, fmt.Errorf("%w: next_renewal must be a YYYY-MM-DD date", ErrInvalidSubscription)
+		}
+		nextRenewal = &t
+	} else if in.BillingDay != nil {
+		t := nextBillingDate(*in.BillingDay, time.Now().UTC())
+		nextRenewal = &t
+	}
+
+	var renewalArg any
+	if nextRenewal != nil {
+		renewalArg = nextRenewal.Format("2006-01-02")
+	}
+
+	err := withTx(ctx, db, func(tx *sql.Tx) error {
+		// The account lock keeps profiles from being added between the
+		// capacity check and the write.
+		if err := lockOwnedAccount(ctx, tx, accountID, userID); err != nil {
+			return err
+		}
+
+		if capacity, ok := planProfileCapacity[plan]; ok {
+			var profiles int
+			if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM netflix_profiles WHERE account_id = $1;`, accountID).Scan(&profiles); err != nil {
+				return fmt.Errorf("count profiles: %w", err)
+			}
+			if profiles > capacity {
+				return fmt.Errorf("%w: plan %s allows %d profiles but the account has %d", ErrInvalidSubscription, plan, capacity, profiles)
+			}
+		}
+
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO subscriptions (account_id, plan, currency, amount, billing_day, next_renewal, payer, notes, due_soon, reminded_for, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, FALSE, NULL, $9)
+			ON CONFLICT (account_id) DO UPDATE SET
+				plan = EXCLUDED.plan,
+				currency = EXCLUDED.currency,
+				amount = EXCLUDED.amount,
+				billing_day = EXCLUDED.billing_day,
+				next_renewal = EXCLUDED.next_renewal,
+				payer = EXCLUDED.payer,
+				notes = EXCLUDED.notes,
+				due_soon = FALSE,
+				reminded_for = CASE WHEN subscriptions.next_renewal = EXCLUDED.next_renewal THEN subscriptions.reminded_for END,
+				updated_at = EXCLUDED.updated_at;`,
+			accountID,
+			plan,
+			currency,
+			in.Amount,
+			in.BillingDay,
+			renewalArg,
+			strings.TrimSpace(in.Payer),
+			strings.TrimSpace(in.Notes),
+			time.Now().UTC().Format(time.RFC3339Nano),
+		); err != nil {
+			return fmt.Errorf("save subscription: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Subscription{}, err
+	}
+
+	return GetSubscription(ctx, db, accountID)
+}
+
+func DeleteSubscription(ctx context.Context, db *sql.DB, accountID, userID int64) error {
+	return withTx(ctx, db, func(tx *sql.Tx) error {
+		if err := lockOwnedAccount(ctx, tx, accountID, userID); err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, `DELETE FROM subscriptions WHERE account_id = $1;`, accountID)
+		if err != nil {
+			return fmt.Errorf("delete subscription: %w", err)
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+}
+
+// loadAccountSubscriptions fills the Subscription field of every account with one query.
+func loadAccountSubscriptions(ctx context.Context, db querier, accounts []models.Account) error {
+	if len(accounts) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(accounts))
+	index := make(map[int64]int, len(accounts))
+	for i := range accounts {
+		ids[i] = accounts[i].ID
+		index[accounts[i].ID] = i
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT `+subscriptionColumns+` FROM subscriptions WHERE account_id = ANY($1);`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("query subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return fmt.Errorf("scan subscription: %w", err)
+		}
+		if i, ok := index[sub.AccountID]; ok {
+			accounts[i].Subscription = &sub
+		}
+	}
+	return rows.Err()
+}
+
+func scanSubscription(row rowScanner) (models.Subscription, error) {
+	var sub models.Subscription
+	var billingDay sql.NullInt64
+	var nextRenewal sql.NullString
+	var updated string
+	if err := row.Scan(&sub.AccountID, &sub.Plan, &sub.Currency, &sub.Amount, &billingDay, &nextRenewal, &sub.Payer, &sub.Notes, &sub.DueSoon, &updated); err != nil {
+		return sub, err
+	}
+	if billingDay.Valid {
+		day := int(billingDay.Int64)
+		sub.BillingDay = &day
+	}
+	if nextRenewal.Valid {
+		t := parseSQLiteTime(nextRenewal.String)
+		sub.NextRenewal = &t
+	}
+	sub.UpdatedAt = parseSQLiteTime(updated)
+	return sub, nil
+}
+
+// nextBillingDate returns the first date on or after from that falls on the
+// billing day, clamped to the last day of shorter months.
+func nextBillingDate(day int, from time.Time) time.Time {
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	candidate := clampedDate(from.Year(), from.Month(), day)
+	if candidate.Before(from) {
+		candidate = clampedDate(from.Year(), from.Month()+1, day)
+	}
+	return candidate
+}
+
+func clampedDate(year int, month time.Month, day int) time.Time {
+	lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func isKnownPlan(plan string) bool {
+	for _, p := range Plans {
+		if p == plan {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,381 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"netflix_central/models"
+)
+
+var ErrInvalidTabGroup = errors.New("invalid tab group")
+
+// tabGroupColors are the colors Chrome offers for tab groups.
+var tabGroupColors = []string{"grey", "blue", "red", "yellow", "green", "pink", "purple", "cyan", "orange"}
+
+// TabGroupInput carries the editable tab group fields.
+type TabGroupInput struct {
+	Name      string
+	Color     string
+	Collapsed bool
+}
+
+// ListTabGroups returns the account's groups in order, each with its tabs.
+func ListTabGroups(ctx context.Context, q querier, accountID int64) ([]models.TabGroup, error) {
+	rows, err := q.QueryContext(
+		ctx,
+		`SELECT id, account_id, name, color, collapsed, position FROM tab_groups WHERE account_id = $1 ORDER BY position ASC, id ASC;`,
+		accountID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query tab groups: %w", err)
+	}
+	defer rows.Close()
+
+	groups := []models.TabGroup{}
+	index := map[int64]int{}
+	for rows.Next() {
+		group, err := scanTabGroup(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan tab group: %w", err)
+		}
+		index[group.ID] = len(groups)
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tabs, err := GetTabs(ctx, q, accountID)
+	if err != nil {
+		return nil, err
+	}
+	for _, tab := range tabs {
+		if tab.GroupID == nil {
+			continue
+		}
+		if i, ok := index[*tab.GroupID]; ok {
+			groups[i].TabIDs = append(groups[i].TabIDs, tab.ID)
+		}
+	}
+	return groups, nil
+}
+
+// CreateTabGroup adds a group after the account's other groups and moves
+// the given tabs into it.
+func CreateTabGroup(ctx context.Context, db *sql.DB, accountID, userID int64, in TabGroupInput, tabIDs []int64) (models.TabGroup, error) {
+	in, err := normalizeTabGroup(in)
+	if err != nil {
+		return models.TabGroup{}, err
+	}
+
+	var group models.TabGroup
+	err = withTx(ctx, db, func(tx *sql.Tx) error {
+		if err := lockOwnedAccount(ctx, tx, accountID, userID); err != nil {
+			return err
+		}
+
+		group = models.TabGroup{AccountID: accountID, Name: in.Name, Color: in.Color, Collapsed: in.Collapsed}
+		if err := tx.QueryRowContext(
+			ctx,
+			`INSERT INTO tab_groups (account_id, name, color, collapsed, position)
+			VALUES ($1, $2, $3, $4, (SELECT COALESCE(MAX(position), 0) + 1 FROM tab_groups WHERE account_id = $1))
+			RETURNING id, position;`,
+			accountID,
+			in.Name,
+			in.Color,
+			in.Collapsed,
+		).Scan(&group.ID, &group.Position); err != nil {
+			return fmt.Errorf("insert tab group: %w", err)
+		}
+
+		if err := assignTabsToGroup(ctx, tx, accountID, group.ID, tabIDs); err != nil {
+			return err
+		}
+		if err := normalizeTabOrder(ctx, tx, accountID); err != nil {
+			return err
+		}
+		if group, err = getTabGroup(ctx, tx, group.ID, accountID); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, "tab_group.create", "tab_group", group.ID, nil, group)
+	})
+	if err != nil {
+		return models.TabGroup{}, err
+	}
+	return group, nil
+}
+
+func UpdateTabGroup(ctx context.Context, db *sql.DB, id, accountID, userID int64, in TabGroupInput) (models.TabGroup, error) {
+	in, err := normalizeTabGroup(in)
+	if err != nil {
+		return models.TabGroup{}, err
+	}
+
+	var group models.TabGroup
+	err = withTx(ctx, db, func(tx *sql.Tx) error {
+		if err := lockOwnedAccount(ctx, tx, accountID, userID); err != nil {
+			return err
+		}
+		before, err := getTabGroup(ctx, tx, id, accountID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(
+			ctx,
+			`UPDATE tab_groups SET name = $1, color = $2, collapsed = $3 WHERE id = $4 AND account_id = $5;`,
+			in.Name,
+			in.Color,
+			in.Collapsed,
+			id,
+			accountID,
+		); err != nil {
+			return fmt.Errorf("update tab group: %w", err)
+		}
+
+		group = before
+		group.Name, group.Color, group.Collapsed = in.Name, in.Color, in.Collapsed
+		return recordAudit(ctx, tx, "tab_group.update", "tab_group", id, before, group)
+	})
+	if err != nil {
+		return models.TabGroup{}, err
+	}
+	return group, nil
+}
+
+// DeleteTabGroup removes a group; its tabs stay and become ungrouped.
+func DeleteTabGroup(ctx context.Context, db *sql.DB, id, accountID, userID int64) error {
+	return withTx(ctx, db, func(tx *sql.Tx) error {
+		if err := lockOwnedAccount(ctx, tx, accountID, userID); err != nil {
+			return err
+		}
+		before, err := getTabGroup(ctx, tx, id, accountID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM tab_groups WHERE id = $1 AND account_id = $2;`, id, accountID); err != nil {
+			return fmt.Errorf("delete tab group: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE tab_groups SET position = position - 1 WHERE account_id = $1 AND position > $2;`, accountID, before.Position); err != nil {
+			return fmt.Errorf("compact tab group positions: %w", err)
+		}
+		if err := normalizeTabOrder(ctx, tx, accountID); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, "tab_group.delete", "tab_group", id, before, nil)
+	})
+}
+
+// ReorderTabGroups sets the order of all of the account's groups; like
+// ReorderTabs it requires every group exactly once.
+func ReorderTabGroups(ctx context.Context, db *sql.DB, accountID, userID int64, orderedIDs []int64) ([]models.TabGroup, error) {
+	if len(orderedIDs) == 0 {
+		return nil, fmt.Errorf("%w: no group ids provided", ErrInvalidTabGroup)
+	}
+
+	var groups []models.TabGroup
+	err := withTx(ctx, db, func(tx *sql.Tx) error {
+		if err := lockOwnedAccount(ctx, tx, accountID, userID); err != nil {
+			return err
+		}
+		current, err := ListTabGroups(ctx, tx, accountID)
+		if err != nil {
+			return err
+		}
+		ids := make([]int64, len(current))
+		for i, group := range current {
+			ids[i] = group.ID
+		}
+		if err := checkFullGroupOrder(ids, orderedIDs); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(
+			ctx,
+			`UPDATE tab_groups SET position = o.pos
+			FROM unnest($1::bigint[]) WITH ORDINALITY AS o(id, pos)
+			WHERE tab_groups.id = o.id AND tab_groups.account_id = $2;`,
+			pq.Array(orderedIDs),
+			accountID,
+		); err != nil {
+			return fmt.Errorf("reorder tab groups: %w", err)
+		}
+		if err := normalizeTabOrder(ctx, tx, accountID); err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, tx, "tab_group.reorder", "account", accountID, map[string]any{"order": ids}, map[string]any{"order": orderedIDs}); err != nil {
+			return err
+		}
+		groups, err = ListTabGroups(ctx, tx, accountID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// SetTabGroup moves a tab into a group, at its end, or out of any group
+// when groupID is nil. It returns the account's tabs in their new order.
+func SetTabGroup(ctx context.Context, db *sql.DB, tabID, accountID, userID int64, groupID *int64) ([]models.Tab, error) {
+	var tabs []models.Tab
+	err := withTx(ctx, db, func(tx *sql.Tx) error {
+		if err := lockOwnedAccount(ctx, tx, accountID, userID); err != nil {
+			return err
+		}
+		before, err := getTab(ctx, tx, tabID, accountID)
+		if err != nil {
+			return err
+		}
+		if groupID != nil {
+			if _, err := getTabGroup(ctx, tx, *groupID, accountID); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return fmt.Errorf("%w: group %d does not belong to this account", ErrInvalidTabGroup, *groupID)
+				}
+				return err
+			}
+		}
+
+		// Give the tab the last position so it ends up at the end of its
+		// new group once the order is normalized.
+		if _, err := tx.ExecContext(
+			ctx,
+			`UPDATE tabs SET group_id = $1, position = (SELECT MAX(position) + 1 FROM tabs WHERE account_id = $2) WHERE id = $3 AND account_id = $2;`,
+			groupID,
+			accountID,
+			tabID,
+		); err != nil {
+			return fmt.Errorf("set tab group: %w", err)
+		}
+		if err := normalizeTabOrder(ctx, tx, accountID); err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, tx, "tab.group", "tab", tabID, map[string]any{"group_id": before.GroupID}, map[string]any{"group_id": groupID}); err != nil {
+			return err
+		}
+		tabs, err = GetTabs(ctx, tx, accountID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tabs, nil
+}
+
+// normalizeTabOrder renumbers the account's tabs so each group's tabs are
+// contiguous: groups come first in group order, then the ungrouped tabs.
+// Within a group, and among ungrouped tabs, the current order is kept.
+func normalizeTabOrder(ctx context.Context, tx *sql.Tx, accountID int64) error {
+	if _, err := tx.ExecContext(
+		ctx,
+		`UPDATE tabs SET position = r.rn FROM (
+			SELECT t.id, ROW_NUMBER() OVER (ORDER BY g.position ASC NULLS LAST, t.position ASC, t.id ASC) AS rn
+			FROM tabs t LEFT JOIN tab_groups g ON g.id = t.group_id
+			WHERE t.account_id = $1
+		) r WHERE tabs.id = r.id AND tabs.position <> r.rn;`,
+		accountID,
+	); err != nil {
+		return fmt.Errorf("normalize tab order: %w", err)
+	}
+	return nil
+}
+
+func checkFullGroupOrder(current, ordered []int64) error {
+	known := make(map[int64]bool, len(current))
+	for _, id := range current {
+		known[id] = true
+	}
+	seen := make(map[int64]bool, len(ordered))
+	for _, id := range ordered {
+		if !known[id] {
+			return fmt.Errorf("%w: group %d does not belong to this account", ErrInvalidTabGroup, id)
+		}
+		if seen[id] {
+			return fmt.Errorf("%w: group %d appears more than once", ErrInvalidTabGroup, id)
+		}
+		seen[id] = true
+	}
+	if len(ordered) != len(current) {
+		return fmt.Errorf("%w: order must list all %d groups of the account, got %d", ErrInvalidTabGroup, len(current), len(ordered))
+	}
+	return nil
+}
+
+func assignTabsToGroup(ctx context.Context, tx *sql.Tx, accountID, groupID int64, tabIDs []int64) error {
+	tabIDs = uniqueIDs(tabIDs)
+	if len(tabIDs) == 0 {
+		return nil
+	}
+	result, err := tx.ExecContext(
+		ctx,
+		`UPDATE tabs SET group_id = $1 WHERE account_id = $2 AND id = ANY($3);`,
+		groupID,
+		accountID,
+		pq.Array(tabIDs),
+	)
+	if err != nil {
+		return fmt.Errorf("assign tabs to group: %w", err)
+	}
+	if n, _ := result.RowsAffected(); int(n) != len(tabIDs) {
+		return fmt.Errorf("%w: tab_ids must belong to this account", ErrInvalidTabGroup)
+	}
+	return nil
+}
+
+func getTabGroup(ctx context.Context, q querier, id, accountID int64) (models.TabGroup, error) {
+	group, err := scanTabGroup(q.QueryRowContext(
+		ctx,
+		`SELECT id, account_id, name, color, collapsed, position FROM tab_groups WHERE id = $1 AND account_id = $2;`,
+		id,
+		accountID,
+	))
+	if err != nil {
+		return models.TabGroup{}, err
+	}
+
+	rows, err := q.QueryContext(ctx, `SELECT id FROM tabs WHERE group_id = $1 ORDER BY position ASC;`, id)
+	if err != nil {
+		return models.TabGroup{}, fmt.Errorf("query group tabs: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tabID int64
+		if err := rows.Scan(&tabID); err != nil {
+			return models.TabGroup{}, fmt.Errorf("scan group tab: %w", err)
+		}
+		group.TabIDs = append(group.TabIDs, tabID)
+	}
+	return group, rows.Err()
+}
+
+func scanTabGroup(row rowScanner) (models.TabGroup, error) {
+	group := models.TabGroup{TabIDs: []int64{}}
+	err := row.Scan(&group.ID, &group.AccountID, &group.Name, &group.Color, &group.Collapsed, &group.Position)
+	return group, err
+}
+
+func normalizeTabGroup(in TabGroupInput) (TabGroupInput, error) {
+	in.Name = strings.TrimSpace(in.Name)
+	if in.Name == "" {
+		return in, fmt.Errorf("%w: name is required", ErrInvalidTabGroup)
+	}
+	if len(in.Name) > 100 {
+		return in, fmt.Errorf("%w: name must be at most 100 characters", ErrInvalidTabGroup)
+	}
+	in.Color = strings.ToLower(strings.TrimSpace(in.Color))
+	if in.Color == "" {
+		in.Color = tabGroupColors[0]
+	}
+	for _, color := range tabGroupColors {
+		if color == in.Color {
+			return in, nil
+		}
+	}
+	return in, fmt.Errorf("%w: color must be one of %s", ErrInvalidTabGroup, strings.Join(tabGroupColors, ", "))
+}
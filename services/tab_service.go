@@ -3,40 +3,60 @@ package services
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"fmt"
 
+	"github.com/lib/pq"
+
 	"netflix_central/models"
 )
 
-var defaultTabs = []models.Tab{
-	{Title: "Netflix Account", URL: "https://www.netflix.com/account", Position: 1},
-	{Title: "Netflix Password", URL: "https://www.netflix.com/password", Position: 2},
-	{Title: "Netflix Login Help", URL: "https://www.netflix.com/id/loginhelp", Position: 3},
-	{Title: "Gmail", URL: "https://mail.google.com/", Position: 4},
-	{Title: "Netflix TV", URL: "https://www.netflix.com/tv2", Position: 5},
+// tabSelect reads tabs with the values of linked library tabs filled in.
+// Callers add conditions on the alias t.
+const tabSelect = `SELECT t.id, t.account_id, COALESCE(t.title, l.title), COALESCE(t.url, l.url), t.library_tab_id,
+	t.library_tab_id IS NOT NULL AND t.title IS NOT NULL, t.library_tab_id IS NOT NULL AND t.url IS NOT NULL,
+	t.group_id, t.pinned, t.enabled, t.open_in_background, t.open_on, t.position, t.version
+	FROM tabs t LEFT JOIN library_tabs l ON l.id = t.library_tab_id`
+
+// InsertDefaultTabs gives a new account its first tabs: those of templateID
+// when set, else of the user's default template. Without a default template
+// the account starts without tabs.
+func InsertDefaultTabs(ctx context.Context, tx *sql.Tx, userID, accountID int64, templateID *int64) error {
+	tabs, err := accountTemplateTabs(ctx, tx, userID, templateID)
+	if err != nil {
+		return err
+	}
+	return appendTabsTx(ctx, tx, accountID, tabs)
 }
 
-func InsertDefaultTabs(ctx context.Context, tx *sql.Tx, accountID int64) error {
-	for _, tab := range defaultTabs {
+// appendTabsTx adds tabs after the account's last tab, in the given order.
+// The caller must hold the account lock.
+func appendTabsTx(ctx context.Context, tx *sql.Tx, accountID int64, tabs []models.Tab) error {
+	if len(tabs) == 0 {
+		return nil
+	}
+	var lastPos int
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(position), 0) FROM tabs WHERE account_id = $1;`, accountID).Scan(&lastPos); err != nil {
+		return fmt.Errorf("get last position: %w", err)
+	}
+	for i, tab := range tabs {
 		if _, err := tx.ExecContext(
 			ctx,
 			`INSERT INTO tabs (account_id, title, url, position) VALUES ($1, $2, $3, $4);`,
 			accountID,
 			tab.Title,
 			tab.URL,
-			tab.Position,
+			lastPos+i+1,
 		); err != nil {
-			return fmt.Errorf("insert default tab: %w", err)
+			return fmt.Errorf("insert tab: %w", err)
 		}
 	}
 	return nil
 }
 
-func GetTabs(ctx context.Context, db *sql.DB, accountID int64) ([]models.Tab, error) {
+func GetTabs(ctx context.Context, db querier, accountID int64) ([]models.Tab, error) {
 	rows, err := db.QueryContext(
 		ctx,
-		`SELECT id, account_id, title, url, position FROM tabs WHERE account_id = $1 ORDER BY position ASC;`,
+		tabSelect+` WHERE t.account_id = $1 ORDER BY t.position ASC;`,
 		accountID,
 	)
 	if err != nil {
@@ -46,8 +66,8 @@ func GetTabs(ctx context.Context, db *sql.DB, accountID int64) ([]models.Tab, er
 
 	var tabs []models.Tab
 	for rows.Next() {
-		var tab models.Tab
-		if err := rows.Scan(&tab.ID, &tab.AccountID, &tab.Title, &tab.URL, &tab.Position); err != nil {
+		tab, err := scanTab(rows)
+		if err != nil {
 			return nil, fmt.Errorf("scan tab: %w", err)
 		}
 		tabs = append(tabs, tab)
@@ -56,120 +76,383 @@ func GetTabs(ctx context.Context, db *sql.DB, accountID int64) ([]models.Tab, er
 	return tabs, rows.Err()
 }
 
-func CreateTab(ctx context.Context, db *sql.DB, accountID int64, title, url string) (models.Tab, error) {
-	var tab models.Tab
-	tx, err := db.BeginTx(ctx, nil)
-	if err != nil {
-		return tab, err
-	}
+// TabOptions are a tab's launch flags. Nil fields, and an empty OpenOn,
+// keep the tab's current value on update and the default on create.
+type TabOptions struct {
+	Pinned           *bool
+	Enabled          *bool
+	OpenInBackground *bool
+	OpenOn           string
+}
 
-	var nextPos sql.NullInt64
-	if err := tx.QueryRowContext(
-		ctx,
-		`SELECT COALESCE(MAX(position), 0) + 1 FROM tabs WHERE account_id = $1;`,
-		accountID,
-	).Scan(&nextPos); err != nil {
-		tx.Rollback()
-		return tab, fmt.Errorf("get next position: %w", err)
+func (o TabOptions) apply(tab *models.Tab) error {
+	if o.Pinned != nil {
+		tab.Pinned = *o.Pinned
+	}
+	if o.Enabled != nil {
+		tab.Enabled = *o.Enabled
+	}
+	if o.OpenInBackground != nil {
+		tab.OpenInBackground = *o.OpenInBackground
+	}
+	switch o.OpenOn {
+	case "":
+	case models.TabOpenAlways, models.TabOpenFirstLaunch, models.TabOpenManual:
+		tab.OpenOn = o.OpenOn
+	default:
+		return fmt.Errorf("%w: open_on must be %s, %s or %s", ErrInvalidTab, models.TabOpenAlways, models.TabOpenFirstLaunch, models.TabOpenManual)
 	}
+	return nil
+}
 
-	result, err := tx.ExecContext(
-		ctx,
-		`INSERT INTO tabs (account_id, title, url, position) VALUES ($1, $2, $3, $4);`,
-		accountID,
-		title,
-		url,
-		nextPos.Int64,
-	)
+// CreateTab appends a tab to the account. The URL is normalized and must
+// pass NormalizeTabURL; placeholders in it must name known variables.
+func CreateTab(ctx context.Context, db *sql.DB, accountID, userID int64, title, url string, opts TabOptions) (models.Tab, error) {
+	title, url, err := normalizeTabInput(title, url)
 	if err != nil {
-		tx.Rollback()
-		return tab, fmt.Errorf("insert tab: %w", err)
+		return models.Tab{}, err
+	}
+	tab := models.Tab{AccountID: accountID, Title: title, URL: url, Enabled: true, OpenOn: models.TabOpenAlways, Version: 1}
+	if err := opts.apply(&tab); err != nil {
+		return models.Tab{}, err
 	}
 
-	id, err := result.LastInsertId()
+	err = withTxRetry(ctx, db, func(tx *sql.Tx) error {
+		// Locking the account serialises concurrent creates; the unique
+		// (account_id, position) constraint and the retry catch any writer
+		// that does not take the lock.
+		if err := lockOwnedAccount(ctx, tx, accountID, userID); err != nil {
+			return err
+		}
+		if err := checkTabURLVariables(ctx, tx, accountID, url); err != nil {
+			return err
+		}
+
+		var nextPos sql.NullInt64
+		if err := tx.QueryRowContext(
+			ctx,
+			`SELECT COALESCE(MAX(position), 0) + 1 FROM tabs WHERE account_id = $1;`,
+			accountID,
+		).Scan(&nextPos); err != nil {
+			return fmt.Errorf("get next position: %w", err)
+		}
+
+		tab.Position = int(nextPos.Int64)
+		if err := tx.QueryRowContext(
+			ctx,
+			`INSERT INTO tabs (account_id, title, url, pinned, enabled, open_in_background, open_on, position)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id;`,
+			accountID,
+			title,
+			url,
+			tab.Pinned,
+			tab.Enabled,
+			tab.OpenInBackground,
+			tab.OpenOn,
+			tab.Position,
+		).Scan(&tab.ID); err != nil {
+			return fmt.Errorf("insert tab: %w", err)
+		}
+
+		return recordAudit(ctx, tx, "tab.create", "tab", tab.ID, nil, tab)
+	})
 	if err != nil {
-		tx.Rollback()
-		return tab, fmt.Errorf("get tab id: %w", err)
+		return models.Tab{}, err
 	}
+	return tab, nil
+}
 
-	if err := tx.Commit(); err != nil {
-		return tab, fmt.Errorf("commit tab: %w", err)
+// UpdateTab changes a tab's title, URL and launch flags. A non-zero ifMatch
+// must equal the tab's current version.
+func UpdateTab(ctx context.Context, db *sql.DB, tabID, accountID, userID int64, title, url string, opts TabOptions, ifMatch int) (models.Tab, error) {
+	title, url, err := normalizeTabInput(title, url)
+	if err != nil {
+		return models.Tab{}, err
 	}
 
-	tab = models.Tab{ID: id, AccountID: accountID, Title: title, URL: url, Position: int(nextPos.Int64)}
+	var tab models.Tab
+	err = withTx(ctx, db, func(tx *sql.Tx) error {
+		if err := lockOwnedAccount(ctx, tx, accountID, userID); err != nil {
+			return err
+		}
+		before, err := getTab(ctx, tx, tabID, accountID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(ifMatch, before.Version); err != nil {
+			return err
+		}
+		if err := checkTabURLVariables(ctx, tx, accountID, url); err != nil {
+			return err
+		}
+
+		tab = before
+		tab.Title, tab.URL, tab.Version = title, url, before.Version+1
+		if err := opts.apply(&tab); err != nil {
+			return err
+		}
+
+		// A linked tab stores only the values that differ from the library
+		// tab, so sending the library's values back clears an override.
+		titleValue, urlValue := sql.NullString{String: title, Valid: true}, sql.NullString{String: url, Valid: true}
+		if before.LibraryTabID != nil {
+			var libTitle, libURL string
+			if err := tx.QueryRowContext(ctx, `SELECT title, url FROM library_tabs WHERE id = $1;`, *before.LibraryTabID).Scan(&libTitle, &libURL); err != nil {
+				return fmt.Errorf("get library tab: %w", err)
+			}
+			titleValue.Valid, urlValue.Valid = title != libTitle, url != libURL
+			tab.TitleOverridden, tab.URLOverridden = titleValue.Valid, urlValue.Valid
+		}
+
+		if _, err := tx.ExecContext(
+			ctx,
+			`UPDATE tabs SET title = $1, url = $2, pinned = $3, enabled = $4, open_in_background = $5, open_on = $6, version = version + 1
+			WHERE id = $7 AND account_id = $8;`,
+			titleValue,
+			urlValue,
+			tab.Pinned,
+			tab.Enabled,
+			tab.OpenInBackground,
+			tab.OpenOn,
+			tabID,
+			accountID,
+		); err != nil {
+			return fmt.Errorf("update tab: %w", err)
+		}
+
+		return recordAudit(ctx, tx, "tab.update", "tab", tabID, before, tab)
+	})
+	if err != nil {
+		return models.Tab{}, err
+	}
 	return tab, nil
 }
 
-func UpdateTab(ctx context.Context, db *sql.DB, tabID, accountID int64, title, url string) (models.Tab, error) {
-	result, err := db.ExecContext(
-		ctx,
-		`UPDATE tabs SET title = $1, url = $2 WHERE id = $3 AND account_id = $4;`,
-		title,
-		url,
-		tabID,
-		accountID,
-	)
-	if err != nil {
-		return models.Tab{}, fmt.Errorf("update tab: %w", err)
+// launchTabs picks the tabs to open for the account. Explicitly chosen tabs
+// are opened whatever their flags; otherwise disabled tabs, manual tabs and,
+// once the account has been opened before, first-launch tabs are left out.
+func launchTabs(account models.Account, tabs []models.Tab, tabIDs []int64) ([]models.Tab, error) {
+	var selected []models.Tab
+	if len(tabIDs) > 0 {
+		chosen := make(map[int64]bool, len(tabIDs))
+		for _, id := range tabIDs {
+			chosen[id] = true
+		}
+		for _, tab := range tabs {
+			if chosen[tab.ID] {
+				selected = append(selected, tab)
+				delete(chosen, tab.ID)
+			}
+		}
+		for _, id := range tabIDs {
+			if chosen[id] {
+				return nil, fmt.Errorf("%w: tab %d does not belong to this account", ErrInvalidTab, id)
+			}
+		}
+		return selected, nil
 	}
 
-	if rows, _ := result.RowsAffected(); rows == 0 {
-		return models.Tab{}, sql.ErrNoRows
+	for _, tab := range tabs {
+		if !tab.Enabled {
+			continue
+		}
+		switch tab.OpenOn {
+		case models.TabOpenManual:
+			continue
+		case models.TabOpenFirstLaunch:
+			if account.LastOpenedAt != nil {
+				continue
+			}
+		}
+		selected = append(selected, tab)
 	}
+	return selected, nil
+}
 
-	var tab models.Tab
-	if err := db.QueryRowContext(
+func getTab(ctx context.Context, q querier, tabID, accountID int64) (models.Tab, error) {
+	return scanTab(q.QueryRowContext(
 		ctx,
-		`SELECT id, account_id, title, url, position FROM tabs WHERE id = $1 AND account_id = $2;`,
+		tabSelect+` WHERE t.id = $1 AND t.account_id = $2 FOR UPDATE OF t;`,
 		tabID,
 		accountID,
-	).Scan(&tab.ID, &tab.AccountID, &tab.Title, &tab.URL, &tab.Position); err != nil {
-		return models.Tab{}, fmt.Errorf("reload tab: %w", err)
-	}
+	))
+}
 
+func scanTab(row rowScanner) (models.Tab, error) {
+	var tab models.Tab
+	var libraryTabID, groupID sql.NullInt64
+	if err := row.Scan(
+		&tab.ID, &tab.AccountID, &tab.Title, &tab.URL, &libraryTabID, &tab.TitleOverridden, &tab.URLOverridden,
+		&groupID, &tab.Pinned, &tab.Enabled, &tab.OpenInBackground, &tab.OpenOn, &tab.Position, &tab.Version,
+	); err != nil {
+		return models.Tab{}, err
+	}
+	if libraryTabID.Valid {
+		tab.LibraryTabID = &libraryTabID.Int64
+	}
+	if groupID.Valid {
+		tab.GroupID = &groupID.Int64
+	}
 	return tab, nil
 }
 
-func DeleteTab(ctx context.Context, db *sql.DB, tabID, accountID int64) error {
-	result, err := db.ExecContext(
-		ctx,
-		`DELETE FROM tabs WHERE id = $1 AND account_id = $2;`,
-		tabID,
-		accountID,
-	)
-	if err != nil {
-		return fmt.Errorf("delete tab: %w", err)
-	}
+func DeleteTab(ctx context.Context, db *sql.DB, tabID, accountID, userID int64) error {
+	return withTx(ctx, db, func(tx *sql.Tx) error {
+		if err := lockOwnedAccount(ctx, tx, accountID, userID); err != nil {
+			return err
+		}
+		before, err := getTab(ctx, tx, tabID, accountID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM tabs WHERE id = $1 AND account_id = $2;`, tabID, accountID); err != nil {
+			return fmt.Errorf("delete tab: %w", err)
+		}
+		// Close the gap so positions stay 1..n.
+		if _, err := tx.ExecContext(ctx, `UPDATE tabs SET position = position - 1 WHERE account_id = $1 AND position > $2;`, accountID, before.Position); err != nil {
+			return fmt.Errorf("compact tab positions: %w", err)
+		}
+		return recordAudit(ctx, tx, "tab.delete", "tab", tabID, before, nil)
+	})
+}
 
-	if rows, _ := result.RowsAffected(); rows == 0 {
-		return sql.ErrNoRows
+// ReorderTabs sets the order of all of the account's tabs at once. orderedIDs
+// must contain every tab of the account exactly once; a partial or stale
+// order is rejected instead of leaving duplicate positions behind. Tabs of a
+// group are kept together in group order, see normalizeTabOrder.
+func ReorderTabs(ctx context.Context, db *sql.DB, accountID, userID int64, orderedIDs []int64) error {
+	if len(orderedIDs) == 0 {
+		return fmt.Errorf("%w: no tab ids provided", ErrInvalidTab)
 	}
 
-	return nil
+	return withTx(ctx, db, func(tx *sql.Tx) error {
+		if err := lockOwnedAccount(ctx, tx, accountID, userID); err != nil {
+			return err
+		}
+		current, err := lockTabOrder(ctx, tx, accountID)
+		if err != nil {
+			return err
+		}
+		if err := checkFullTabOrder(current, orderedIDs); err != nil {
+			return err
+		}
+
+		if err := writeTabOrder(ctx, tx, accountID, orderedIDs); err != nil {
+			return err
+		}
+		if err := normalizeTabOrder(ctx, tx, accountID); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, "tab.reorder", "account", accountID, map[string]any{"order": current}, map[string]any{"order": orderedIDs})
+	})
 }
 
-func ReorderTabs(ctx context.Context, db *sql.DB, accountID int64, orderedIDs []int64) error {
-	if len(orderedIDs) == 0 {
-		return errors.New("no tab ids provided")
+// MoveTab moves one tab to a 1-based position and shifts the tabs in
+// between, returning the account's tabs in their new order. A grouped tab
+// stays inside its group, so the final position may differ.
+func MoveTab(ctx context.Context, db *sql.DB, tabID, accountID, userID int64, position int) ([]models.Tab, error) {
+	var tabs []models.Tab
+	err := withTx(ctx, db, func(tx *sql.Tx) error {
+		if err := lockOwnedAccount(ctx, tx, accountID, userID); err != nil {
+			return err
+		}
+		current, err := lockTabOrder(ctx, tx, accountID)
+		if err != nil {
+			return err
+		}
+
+		from := -1
+		for i, id := range current {
+			if id == tabID {
+				from = i
+				break
+			}
+		}
+		if from < 0 {
+			return sql.ErrNoRows
+		}
+		if position < 1 || position > len(current) {
+			return fmt.Errorf("%w: position must be between 1 and %d", ErrInvalidTab, len(current))
+		}
+
+		if from != position-1 {
+			order := make([]int64, 0, len(current))
+			order = append(order, current[:from]...)
+			order = append(order, current[from+1:]...)
+			order = append(order[:position-1], append([]int64{tabID}, order[position-1:]...)...)
+
+			if err := writeTabOrder(ctx, tx, accountID, order); err != nil {
+				return err
+			}
+			if err := normalizeTabOrder(ctx, tx, accountID); err != nil {
+				return err
+			}
+			if err := recordAudit(ctx, tx, "tab.move", "tab", tabID, map[string]any{"position": from + 1}, map[string]any{"position": position}); err != nil {
+				return err
+			}
+		}
+
+		tabs, err = GetTabs(ctx, tx, accountID)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
+	return tabs, nil
+}
 
-	tx, err := db.BeginTx(ctx, nil)
+// lockTabOrder locks the account's tabs and returns their ids in display order.
+func lockTabOrder(ctx context.Context, tx *sql.Tx, accountID int64) ([]int64, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM tabs WHERE account_id = $1 ORDER BY position ASC, id ASC FOR UPDATE;`, accountID)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("lock tabs: %w", err)
 	}
+	defer rows.Close()
 
-	for idx, id := range orderedIDs {
-		if _, err := tx.ExecContext(
-			ctx,
-			`UPDATE tabs SET position = $1 WHERE id = $2 AND account_id = $3;`,
-			idx+1,
-			id,
-			accountID,
-		); err != nil {
-			tx.Rollback()
-			return fmt.Errorf("reorder tab %d: %w", id, err)
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan tab id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func checkFullTabOrder(current, ordered []int64) error {
+	known := make(map[int64]bool, len(current))
+	for _, id := range current {
+		known[id] = true
+	}
+	seen := make(map[int64]bool, len(ordered))
+	for _, id := range ordered {
+		if !known[id] {
+			return fmt.Errorf("%w: tab %d does not belong to this account", ErrInvalidTab, id)
 		}
+		if seen[id] {
+			return fmt.Errorf("%w: tab %d appears more than once", ErrInvalidTab, id)
+		}
+		seen[id] = true
+	}
+	if len(ordered) != len(current) {
+		return fmt.Errorf("%w: order must list all %d tabs of the account, got %d", ErrInvalidTab, len(current), len(ordered))
 	}
+	return nil
+}
 
-	return tx.Commit()
+// writeTabOrder renumbers the tabs 1..n in one statement.
+func writeTabOrder(ctx context.Context, tx *sql.Tx, accountID int64, order []int64) error {
+	if _, err := tx.ExecContext(
+		ctx,
+		`UPDATE tabs SET position = o.pos
+		FROM unnest($1::bigint[]) WITH ORDINALITY AS o(id, pos)
+		WHERE tabs.id = o.id AND tabs.account_id = $2;`,
+		pq.Array(order),
+		accountID,
+	); err != nil {
+		return fmt.Errorf("reorder tabs: %w", err)
+	}
+	return nil
 }
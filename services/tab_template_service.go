@@ -0,0 +1,469 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"netflix_central/models"
+)
+
+var ErrInvalidTemplate = errors.New("invalid tab template")
+
+const maxTemplateTabs = 50
+
+// starterTemplateTabs fill the "Default" template every user gets once, when
+// registering; users from before templates get it with their next new
+// account. From then on the template is the user's to edit, unset or delete.
+var starterTemplateTabs = []models.TabTemplateItem{
+	{Title: "Netflix Account", URL: "https://www.netflix.com/account"},
+	{Title: "Netflix Password", URL: "https://www.netflix.com/password"},
+	{Title: "Netflix Login Help", URL: "https://www.netflix.com/loginhelp"},
+	{Title: "Gmail", URL: "https://mail.google.com/"},
+	{Title: "Netflix TV", URL: "https://www.netflix.com/tv2"},
+}
+
+// Modes for ApplyTabTemplate.
+const (
+	TemplateMerge   = "merge"
+	TemplateReplace = "replace"
+)
+
+// TabTemplateInput carries the editable template fields. Tabs are stored in
+// the given order.
+type TabTemplateInput struct {
+	Name      string
+	IsDefault bool
+	Tabs      []models.TabTemplateItem
+}
+
+func ListTabTemplates(ctx context.Context, db *sql.DB, userID int64) ([]models.TabTemplate, error) {
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT id, user_id, name, is_default, created_at, updated_at FROM tab_templates WHERE user_id = $1 ORDER BY LOWER(name) ASC;`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query tab templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []models.TabTemplate{}
+	for rows.Next() {
+		tpl, err := scanTabTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan tab template: %w", err)
+		}
+		templates = append(templates, tpl)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := loadTemplateItems(ctx, db, templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+func GetTabTemplate(ctx context.Context, q querier, id, userID int64) (models.TabTemplate, error) {
+	tpl, err := scanTabTemplate(q.QueryRowContext(
+		ctx,
+		`SELECT id, user_id, name, is_default, created_at, updated_at FROM tab_templates WHERE id = $1 AND user_id = $2;`,
+		id,
+		userID,
+	))
+	if err != nil {
+		return models.TabTemplate{}, err
+	}
+	templates := []models.TabTemplate{tpl}
+	if err := loadTemplateItems(ctx, q, templates); err != nil {
+		return models.TabTemplate{}, err
+	}
+	return templates[0], nil
+}
+
+func CreateTabTemplate(ctx context.Context, db *sql.DB, userID int64, in TabTemplateInput) (models.TabTemplate, error) {
+	in, err := normalizeTabTemplate(in)
+	if err != nil {
+		return models.TabTemplate{}, err
+	}
+
+	var id int64
+	err = withTx(ctx, db, func(tx *sql.Tx) error {
+		if in.IsDefault {
+			if err := clearDefaultTemplate(ctx, tx, userID); err != nil {
+				return err
+			}
+		}
+		now := time.Now().UTC().Format(time.RFC3339Nano)
+		if err := tx.QueryRowContext(
+			ctx,
+			`INSERT INTO tab_templates (user_id, name, is_default, created_at, updated_at) VALUES ($1, $2, $3, $4, $4) RETURNING id;`,
+			userID,
+			in.Name,
+			in.IsDefault,
+			now,
+		).Scan(&id); err != nil {
+			if isUniqueViolation(err) {
+				return fmt.Errorf("%w: a template named %q already exists", ErrInvalidTemplate, in.Name)
+			}
+			return fmt.Errorf("insert tab template: %w", err)
+		}
+		if err := insertTemplateItems(ctx, tx, id, in.Tabs); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, "tab_template.create", "tab_template", id, nil, in)
+	})
+	if err != nil {
+		return models.TabTemplate{}, err
+	}
+	return GetTabTemplate(ctx, db, id, userID)
+}
+
+// UpdateTabTemplate renames the template and replaces its tabs. Accounts
+// created from it earlier keep their tabs.
+func UpdateTabTemplate(ctx context.Context, db *sql.DB, id, userID int64, in TabTemplateInput) (models.TabTemplate, error) {
+	in, err := normalizeTabTemplate(in)
+	if err != nil {
+		return models.TabTemplate{}, err
+	}
+
+	err = withTx(ctx, db, func(tx *sql.Tx) error {
+		before, err := lockTabTemplate(ctx, tx, id, userID)
+		if err != nil {
+			return err
+		}
+		if in.IsDefault && !before.IsDefault {
+			if err := clearDefaultTemplate(ctx, tx, userID); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.ExecContext(
+			ctx,
+			`UPDATE tab_templates SET name = $1, is_default = $2, updated_at = $3 WHERE id = $4;`,
+			in.Name,
+			in.IsDefault,
+			time.Now().UTC().Format(time.RFC3339Nano),
+			id,
+		); err != nil {
+			if isUniqueViolation(err) {
+				return fmt.Errorf("%w: a template named %q already exists", ErrInvalidTemplate, in.Name)
+			}
+			return fmt.Errorf("update tab template: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM tab_template_items WHERE template_id = $1;`, id); err != nil {
+			return fmt.Errorf("clear template tabs: %w", err)
+		}
+		if err := insertTemplateItems(ctx, tx, id, in.Tabs); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, "tab_template.update", "tab_template", id, before, in)
+	})
+	if err != nil {
+		return models.TabTemplate{}, err
+	}
+	return GetTabTemplate(ctx, db, id, userID)
+}
+
+// DeleteTabTemplate removes a template. Deleting the default template makes
+// new accounts start without tabs.
+func DeleteTabTemplate(ctx context.Context, db *sql.DB, id, userID int64) error {
+	return withTx(ctx, db, func(tx *sql.Tx) error {
+		before, err := lockTabTemplate(ctx, tx, id, userID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM tab_templates WHERE id = $1;`, id); err != nil {
+			return fmt.Errorf("delete tab template: %w", err)
+		}
+		return recordAudit(ctx, tx, "tab_template.delete", "tab_template", id, before, nil)
+	})
+}
+
+// ApplyTabTemplate copies a template's tabs into existing accounts. Merge
+// appends the tabs whose URL the account does not have yet; replace removes
+// every tab of the account first. All accounts are updated in one
+// transaction, so an unknown account changes nothing.
+func ApplyTabTemplate(ctx context.Context, db *sql.DB, id, userID int64, accountIDs []int64, mode string) ([]models.TemplateApplyResult, error) {
+	if mode == "" {
+		mode = TemplateMerge
+	}
+	if mode != TemplateMerge && mode != TemplateReplace {
+		return nil, fmt.Errorf("%w: mode must be merge or replace", ErrInvalidTemplate)
+	}
+	accountIDs = uniqueIDs(accountIDs)
+	if len(accountIDs) == 0 {
+		return nil, fmt.Errorf("%w: account_ids is required", ErrInvalidTemplate)
+	}
+	if len(accountIDs) > maxBulkAccounts {
+		return nil, fmt.Errorf("%w: at most %d accounts per request", ErrInvalidTemplate, maxBulkAccounts)
+	}
+
+	results := make([]models.TemplateApplyResult, 0, len(accountIDs))
+	err := withTx(ctx, db, func(tx *sql.Tx) error {
+		tpl, err := GetTabTemplate(ctx, tx, id, userID)
+		if err != nil {
+			return err
+		}
+
+		for _, accountID := range accountIDs {
+			if err := lockOwnedAccount(ctx, tx, accountID, userID); err != nil {
+				return err
+			}
+			res, err := applyTemplateTx(ctx, tx, accountID, tpl.Tabs, mode)
+			if err != nil {
+				return fmt.Errorf("account %d: %w", accountID, err)
+			}
+			if err := recordAudit(ctx, tx, "tab_template.apply", "account", accountID, nil, map[string]any{
+				"template_id": id,
+				"mode":        mode,
+				"added":       res.Added,
+				"removed":     res.Removed,
+			}); err != nil {
+				return err
+			}
+			results = append(results, res)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func applyTemplateTx(ctx context.Context, tx *sql.Tx, accountID int64, items []models.TabTemplateItem, mode string) (models.TemplateApplyResult, error) {
+	res := models.TemplateApplyResult{AccountID: accountID}
+
+	existing := map[string]bool{}
+	if mode == TemplateReplace {
+		result, err := tx.ExecContext(ctx, `DELETE FROM tabs WHERE account_id = $1;`, accountID)
+		if err != nil {
+			return res, fmt.Errorf("clear tabs: %w", err)
+		}
+		removed, _ := result.RowsAffected()
+		res.Removed = int(removed)
+	} else {
+		tabs, err := GetTabs(ctx, tx, accountID)
+		if err != nil {
+			return res, err
+		}
+		for _, tab := range tabs {
+			existing[tab.URL] = true
+		}
+	}
+
+	vars, err := GetAccountVariables(ctx, tx, accountID)
+	if err != nil {
+		return res, err
+	}
+	var toAdd []models.Tab
+	for _, item := range items {
+		if existing[item.URL] {
+			continue
+		}
+		if name, ok := undefinedTabVariable(item.URL, vars); ok {
+			return res, fmt.Errorf("%w: tab %q uses {{%s}}, which the account does not define", ErrInvalidTemplate, item.Title, name)
+		}
+		existing[item.URL] = true
+		toAdd = append(toAdd, models.Tab{Title: item.Title, URL: item.URL})
+	}
+	if err := appendTabsTx(ctx, tx, accountID, toAdd); err != nil {
+		return res, err
+	}
+	res.Added = len(toAdd)
+	return res, nil
+}
+
+// accountTemplateTabs returns the tabs a new account starts with: those of
+// templateID when given, else those of the user's default template, if any.
+func accountTemplateTabs(ctx context.Context, tx *sql.Tx, userID int64, templateID *int64) ([]models.Tab, error) {
+	id := int64(0)
+	if templateID != nil {
+		id = *templateID
+	} else if err := ensureStarterTemplate(ctx, tx, userID); err != nil {
+		return nil, err
+	} else if err := tx.QueryRowContext(
+		ctx,
+		`SELECT id FROM tab_templates WHERE user_id = $1 AND is_default;`,
+		userID,
+	).Scan(&id); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("query default template: %w", err)
+	}
+	if id == 0 {
+		return nil, nil
+	}
+
+	tpl, err := GetTabTemplate(ctx, tx, id, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: template not found", ErrInvalidTemplate)
+	}
+	if err != nil {
+		return nil, err
+	}
+	tabs := make([]models.Tab, len(tpl.Tabs))
+	for i, item := range tpl.Tabs {
+		if err := checkNewAccountTabURL(item.URL); err != nil {
+			return nil, fmt.Errorf("%w: template %q, tab %d: %v", ErrInvalidTemplate, tpl.Name, i+1, err)
+		}
+		tabs[i] = models.Tab{Title: item.Title, URL: item.URL, Position: i + 1}
+	}
+	return tabs, nil
+}
+
+// ensureStarterTemplate creates the user's "Default" template from
+// starterTemplateTabs, once: users who already have templates are only
+// marked as seeded. CreateUser and accountTemplateTabs call it.
+func ensureStarterTemplate(ctx context.Context, tx *sql.Tx, userID int64) error {
+	var seeded bool
+	if err := tx.QueryRowContext(ctx, `SELECT tab_templates_seeded FROM users WHERE id = $1;`, userID).Scan(&seeded); err != nil {
+		return fmt.Errorf("check starter template: %w", err)
+	}
+	if seeded {
+		return nil
+	}
+	// Recheck under the user's lock so concurrent first uses seed once.
+	if err := tx.QueryRowContext(ctx, `SELECT tab_templates_seeded FROM users WHERE id = $1 FOR UPDATE;`, userID).Scan(&seeded); err != nil {
+		return fmt.Errorf("lock user: %w", err)
+	}
+	if seeded {
+		return nil
+	}
+
+	var hasTemplates bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM tab_templates WHERE user_id = $1);`, userID).Scan(&hasTemplates); err != nil {
+		return fmt.Errorf("check tab templates: %w", err)
+	}
+	if !hasTemplates {
+		now := time.Now().UTC().Format(time.RFC3339Nano)
+		var id int64
+		if err := tx.QueryRowContext(
+			ctx,
+			`INSERT INTO tab_templates (user_id, name, is_default, created_at, updated_at) VALUES ($1, 'Default', TRUE, $2, $2) RETURNING id;`,
+			userID,
+			now,
+		).Scan(&id); err != nil {
+			return fmt.Errorf("insert starter template: %w", err)
+		}
+		if err := insertTemplateItems(ctx, tx, id, starterTemplateTabs); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET tab_templates_seeded = TRUE WHERE id = $1;`, userID); err != nil {
+		return fmt.Errorf("mark starter template: %w", err)
+	}
+	return nil
+}
+
+func lockTabTemplate(ctx context.Context, tx *sql.Tx, id, userID int64) (models.TabTemplate, error) {
+	return scanTabTemplate(tx.QueryRowContext(
+		ctx,
+		`SELECT id, user_id, name, is_default, created_at, updated_at FROM tab_templates WHERE id = $1 AND user_id = $2 FOR UPDATE;`,
+		id,
+		userID,
+	))
+}
+
+func clearDefaultTemplate(ctx context.Context, tx *sql.Tx, userID int64) error {
+	if _, err := tx.ExecContext(ctx, `UPDATE tab_templates SET is_default = FALSE WHERE user_id = $1 AND is_default;`, userID); err != nil {
+		return fmt.Errorf("clear default template: %w", err)
+	}
+	return nil
+}
+
+func insertTemplateItems(ctx context.Context, tx *sql.Tx, templateID int64, items []models.TabTemplateItem) error {
+	for i, item := range items {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO tab_template_items (template_id, title, url, position) VALUES ($1, $2, $3, $4);`,
+			templateID,
+			item.Title,
+			item.URL,
+			i+1,
+		); err != nil {
+			return fmt.Errorf("insert template tab: %w", err)
+		}
+	}
+	return nil
+}
+
+// loadTemplateItems fills the Tabs field of every template with one query.
+func loadTemplateItems(ctx context.Context, q querier, templates []models.TabTemplate) error {
+	if len(templates) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(templates))
+	index := make(map[int64]int, len(templates))
+	for i := range templates {
+		ids[i] = templates[i].ID
+		index[templates[i].ID] = i
+		templates[i].Tabs = []models.TabTemplateItem{}
+	}
+
+	rows, err := q.QueryContext(
+		ctx,
+		`SELECT template_id, title, url, position FROM tab_template_items WHERE template_id = ANY($1) ORDER BY template_id, position ASC;`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return fmt.Errorf("query template tabs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var templateID int64
+		var item models.TabTemplateItem
+		if err := rows.Scan(&templateID, &item.Title, &item.URL, &item.Position); err != nil {
+			return fmt.Errorf("scan template tab: %w", err)
+		}
+		if i, ok := index[templateID]; ok {
+			templates[i].Tabs = append(templates[i].Tabs, item)
+		}
+	}
+	return rows.Err()
+}
+
+func scanTabTemplate(row rowScanner) (models.TabTemplate, error) {
+	var tpl models.TabTemplate
+	var createdAt, updatedAt string
+	if err := row.Scan(&tpl.ID, &tpl.UserID, &tpl.Name, &tpl.IsDefault, &createdAt, &updatedAt); err != nil {
+		return models.TabTemplate{}, err
+	}
+	tpl.CreatedAt = parseSQLiteTime(createdAt)
+	tpl.UpdatedAt = parseSQLiteTime(updatedAt)
+	return tpl, nil
+}
+
+func normalizeTabTemplate(in TabTemplateInput) (TabTemplateInput, error) {
+	in.Name = strings.TrimSpace(in.Name)
+	if in.Name == "" {
+		return in, fmt.Errorf("%w: name is required", ErrInvalidTemplate)
+	}
+	if len(in.Tabs) == 0 {
+		return in, fmt.Errorf("%w: a template needs at least one tab", ErrInvalidTemplate)
+	}
+	if len(in.Tabs) > maxTemplateTabs {
+		return in, fmt.Errorf("%w: at most %d tabs per template", ErrInvalidTemplate, maxTemplateTabs)
+	}
+
+	for i := range in.Tabs {
+		tab := &in.Tabs[i]
+		title, link, err := normalizeTabInput(tab.Title, tab.URL)
+		if err == nil && in.IsDefault {
+			// The default template fills accounts as they are created.
+			err = checkNewAccountTabURL(link)
+		}
+		if err != nil {
+			return in, fmt.Errorf("%w: tab %d: %v", ErrInvalidTemplate, i+1, err)
+		}
+		tab.Title, tab.URL, tab.Position = title, link, i+1
+	}
+	return in, nil
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStarterTemplateIsSeededByAccountCreateNotByList(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	userID := createTestUser(t, db)
+
+	templates, err := ListTabTemplates(ctx, db, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) != 0 {
+		t.Fatalf("listing templates seeded %d templates, want none", len(templates))
+	}
+
+	acc := createTestAccount(t, db, userID)
+	templates, err = ListTabTemplates(ctx, db, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) != 1 || !templates[0].IsDefault || len(templates[0].Tabs) != len(starterTemplateTabs) {
+		t.Fatalf("templates after the first account = %+v, want the starter template", templates)
+	}
+	tabs, err := GetTabs(ctx, db, acc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tabs) != len(starterTemplateTabs) {
+		t.Fatalf("new account has %d tabs, want %d", len(tabs), len(starterTemplateTabs))
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var ErrInvalidTab = errors.New("invalid tab")
+
+const maxTabURLLength = 2048
+
+// NormalizeTabURL checks that raw is an absolute http(s) URL on an allowed
+// domain and returns it in canonical form: lower-case scheme and host, no
+// default port and at least "/" as path. Credentials in the URL are
+// rejected so they never end up in tabs or exports.
+func NormalizeTabURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", fmt.Errorf("%w: url is required", ErrInvalidTab)
+	}
+	if len(raw) > maxTabURLLength {
+		return "", fmt.Errorf("%w: url is longer than %d characters", ErrInvalidTab, maxTabURLLength)
+	}
+	if strings.IndexFunc(raw, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0 {
+		return "", fmt.Errorf("%w: url must not contain spaces or control characters", ErrInvalidTab)
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("%w: url cannot be parsed", ErrInvalidTab)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("%w: only http and https URLs are allowed", ErrInvalidTab)
+	}
+	if u.Opaque != "" || u.Host == "" {
+		return "", fmt.Errorf("%w: url must be absolute, like https://www.netflix.com/", ErrInvalidTab)
+	}
+	if u.User != nil {
+		return "", fmt.Errorf("%w: url must not contain credentials", ErrInvalidTab)
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return "", fmt.Errorf("%w: url has no host", ErrInvalidTab)
+	}
+	port := u.Port()
+	if port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return "", fmt.Errorf("%w: invalid port %q", ErrInvalidTab, port)
+		}
+		if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+			port = ""
+		}
+	}
+	if !hostAllowed(host, tabURLAllowlist()) {
+		return "", fmt.Errorf("%w: %s is not on the allowed domain list", ErrInvalidTab, host)
+	}
+
+	u.Host = host
+	if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]"
+	}
+	if port != "" {
+		u.Host += ":" + port
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u.String(), nil
+}
+
+// normalizeTabInput trims the title and normalizes the URL of a tab, which
+// may contain placeholders. An empty title falls back to the host.
+func normalizeTabInput(title, rawURL string) (string, string, error) {
+	link, err := normalizeTabURLTemplate(rawURL)
+	if err != nil {
+		return "", "", err
+	}
+	title = strings.TrimSpace(title)
+	if title == "" {
+		u, _ := url.Parse(link)
+		title = u.Hostname()
+	}
+	return title, link, nil
+}
+
+// tabURLAllowlist reads TAB_URL_ALLOWLIST, a comma-separated list of
+// domains. An empty list allows every domain.
+func tabURLAllowlist() []string {
+	var domains []string
+	for _, d := range strings.Split(os.Getenv("TAB_URL_ALLOWLIST"), ",") {
+		d = strings.ToLower(strings.TrimSpace(d))
+		d = strings.TrimPrefix(strings.TrimPrefix(d, "*"), ".")
+		if d != "" {
+			domains = append(domains, d)
+		}
+	}
+	return domains
+}
+
+// hostAllowed reports whether host is one of the domains or a subdomain of one.
+func hostAllowed(host string, domains []string) bool {
+	if len(domains) == 0 {
+		return true
+	}
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"netflix_central/models"
+)
+
+// Tab URLs may contain placeholders like {{netflix_email}}. They are stored
+// as written and replaced, URL-escaped, only when Chrome is launched.
+
+var (
+	tabURLPlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+	tabVariableName   = regexp.MustCompile(`^[a-z][a-z0-9_]{0,39}$`)
+)
+
+// builtinTabVariables are filled in from the account itself and cannot be
+// redefined as account variables.
+var builtinTabVariables = []string{"id", "label", "netflix_email"}
+
+// placeholderSentinel stands in for placeholders while a URL template is
+// validated; it only uses characters URL normalization leaves alone.
+const placeholderSentinel = "zzncvar"
+
+// normalizeTabURLTemplate normalizes a tab URL that may contain
+// placeholders. Placeholders are written as {{name}} and may appear in the
+// path, query and fragment, but not in the scheme or host, so the domain
+// checks of NormalizeTabURL still hold once they are filled in.
+func normalizeTabURLTemplate(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "{{") && !strings.Contains(raw, "}}") {
+		return NormalizeTabURL(raw)
+	}
+	if strings.Contains(raw, placeholderSentinel) {
+		return "", fmt.Errorf("%w: url must not contain %q", ErrInvalidTab, placeholderSentinel)
+	}
+
+	var names []string
+	masked := tabURLPlaceholder.ReplaceAllStringFunc(raw, func(m string) string {
+		name := tabURLPlaceholder.FindStringSubmatch(m)[1]
+		names = append(names, name)
+		return placeholderSentinel + strconv.Itoa(len(names)-1) + "z"
+	})
+	if strings.Contains(masked, "{{") || strings.Contains(masked, "}}") {
+		return "", fmt.Errorf("%w: placeholders must look like {{name}}", ErrInvalidTab)
+	}
+	for _, name := range names {
+		if !tabVariableName.MatchString(name) {
+			return "", fmt.Errorf("%w: invalid variable name %q", ErrInvalidTab, name)
+		}
+	}
+
+	link, err := NormalizeTabURL(masked)
+	if err != nil {
+		return "", err
+	}
+	if u, _ := url.Parse(link); strings.Contains(u.Host, placeholderSentinel) {
+		return "", fmt.Errorf("%w: placeholders are not allowed in the host", ErrInvalidTab)
+	}
+	for i := len(names) - 1; i >= 0; i-- {
+		link = strings.ReplaceAll(link, placeholderSentinel+strconv.Itoa(i)+"z", "{{"+names[i]+"}}")
+	}
+	return link, nil
+}
+
+// tabURLVariables returns the variable names used in a tab URL.
+func tabURLVariables(raw string) []string {
+	var names []string
+	for _, m := range tabURLPlaceholder.FindAllStringSubmatch(raw, -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
+
+// undefinedTabVariable returns the first variable used in link that is
+// neither built in nor one of vars.
+func undefinedTabVariable(link string, vars map[string]string) (string, bool) {
+	for _, name := range tabURLVariables(link) {
+		if _, ok := vars[name]; !ok && !isBuiltinTabVariable(name) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func isBuiltinTabVariable(name string) bool {
+	for _, builtin := range builtinTabVariables {
+		if builtin == name {
+			return true
+		}
+	}
+	return false
+}
+
+// tabVariables returns the values placeholders of the account's tabs are
+// replaced with: the built-in ones and the account's own variables.
+func tabVariables(account models.Account, custom map[string]string) map[string]string {
+	vars := make(map[string]string, len(custom)+len(builtinTabVariables))
+	for name, value := range custom {
+		vars[name] = value
+	}
+	vars["id"] = strconv.FormatInt(account.ID, 10)
+	vars["label"] = account.Label
+	vars["netflix_email"] = account.NetflixEmail
+	return vars
+}
+
+// renderTabURL fills in the placeholders of a tab URL. Values are escaped
+// for the part of the URL they land in and the result is checked again with
+// NormalizeTabURL.
+func renderTabURL(raw string, vars map[string]string) (string, error) {
+	queryStart := strings.IndexAny(raw, "?#")
+	var b strings.Builder
+	last := 0
+	for _, loc := range tabURLPlaceholder.FindAllStringSubmatchIndex(raw, -1) {
+		name := raw[loc[2]:loc[3]]
+		value, ok := vars[name]
+		if !ok {
+			return "", fmt.Errorf("%w: variable %q is not defined for this account", ErrInvalidTab, name)
+		}
+		b.WriteString(raw[last:loc[0]])
+		if queryStart >= 0 && loc[0] > queryStart {
+			b.WriteString(url.QueryEscape(value))
+		} else {
+			b.WriteString(url.PathEscape(value))
+		}
+		last = loc[1]
+	}
+	b.WriteString(raw[last:])
+	return NormalizeTabURL(b.String())
+}
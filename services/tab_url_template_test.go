@@ -0,0 +1,68 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestUndefinedTabVariable(t *testing.T) {
+	vars := map[string]string{"profile": "2"}
+	cases := []struct {
+		link string
+		want string
+	}{
+		{"https://www.netflix.com/browse", ""},
+		{"https://mail.google.com/mail/u/0/#search/{{netflix_email}}", ""},
+		{"https://www.netflix.com/{{profile}}/{{id}}", ""},
+		{"https://www.netflix.com/{{profile}}?pin={{pin}}", "pin"},
+	}
+	for _, tc := range cases {
+		got, ok := undefinedTabVariable(tc.link, vars)
+		if got != tc.want || ok != (tc.want != "") {
+			t.Errorf("undefinedTabVariable(%q) = %q, %v; want %q", tc.link, got, ok, tc.want)
+		}
+	}
+}
+
+func TestValidateImportRowRejectsAccountVariables(t *testing.T) {
+	row := normalizeImportRow(ImportRow{
+		Label: "Imported",
+		Email: "imported@example.com",
+		Tabs: []ImportTab{
+			{Title: "Mail", URL: "https://mail.google.com/mail/u/0/#search/{{netflix_email}}"},
+			{Title: "Profile", URL: "https://www.netflix.com/{{profile}}"},
+		},
+	})
+	errs := validateImportRow(row)
+	if len(errs) != 1 || !strings.HasPrefix(errs[0], "tab 2:") || !strings.Contains(errs[0], "{{profile}}") {
+		t.Fatalf("validateImportRow errors = %q, want one for tab 2 naming {{profile}}", errs)
+	}
+}
+
+func TestRenderTabURL(t *testing.T) {
+	vars := map[string]string{"netflix_email": "a+b@example.com", "profile": "Kids & Teens/2", "pin": "1 2#3"}
+	cases := []struct {
+		link string
+		want string
+	}{
+		{"https://www.netflix.com/browse", "https://www.netflix.com/browse"},
+		{"https://www.netflix.com/{{profile}}/x", "https://www.netflix.com/Kids%20&%20Teens%2F2/x"},
+		{"https://www.netflix.com/p?name={{profile}}&pin={{pin}}", "https://www.netflix.com/p?name=Kids+%26+Teens%2F2&pin=1+2%233"},
+		{"https://mail.google.com/mail/u/0/#search/{{netflix_email}}", "https://mail.google.com/mail/u/0/#search/a%2Bb%40example.com"},
+		{"https://www.netflix.com/{{ profile }}?q={{pin}}", "https://www.netflix.com/Kids%20&%20Teens%2F2?q=1+2%233"},
+		{"https://www.netflix.com/{{missing}}", ""},
+	}
+	for _, tc := range cases {
+		got, err := renderTabURL(tc.link, vars)
+		if tc.want == "" {
+			if !errors.Is(err, ErrInvalidTab) || !strings.Contains(err.Error(), "missing") {
+				t.Errorf("renderTabURL(%q) = %q, %v; want an undefined variable error", tc.link, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("renderTabURL(%q) = %q, %v; want %q", tc.link, got, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"netflix_central/models"
+)
+
+var (
+	ErrInvalidTag   = errors.New("invalid tag")
+	ErrTagNameTaken = errors.New("tag name already exists")
+)
+
+const defaultTagColor = "#9ca3af"
+
+var tagColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+func ListTags(ctx context.Context, db *sql.DB, userID int64) ([]models.Tag, error) {
+	rows, err := db.QueryContext(ctx, `SELECT id, user_id, name, color, created_at FROM tags WHERE user_id = $1 ORDER BY LOWER(name) ASC;`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("query tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+func GetTag(ctx context.Context, q querier, id, userID int64) (models.Tag, error) {
+	return scanTag(q.QueryRowContext(ctx, `SELECT id, user_id, name, color, created_at FROM tags WHERE id = $1 AND user_id = $2;`, id, userID))
+}
+
+func CreateTag(ctx context.Context, db *sql.DB, userID int64, name, color string) (models.Tag, error) {
+	name, color, err := normalizeTag(name, color)
+	if err != nil {
+		return models.Tag{}, err
+	}
+
+	createdAt := time.Now().UTC().Format(time.RFC3339Nano)
+	var id int64
+	if err := db.QueryRowContext(
+		ctx,
+		`INSERT INTO tags (user_id, name, color, created_at) VALUES ($1, $2, $3, $4) RETURNING id;`,
+		userID,
+		name,
+		color,
+		createdAt,
+	).Scan(&id); err != nil {
+		if isUniqueViolation(err) {
+			return models.Tag{}, ErrTagNameTaken
+		}
+		return models.Tag{}, fmt.Errorf("insert tag: %w", err)
+	}
+
+	return models.Tag{ID: id, UserID: userID, Name: name, Color: color, CreatedAt: parseSQLiteTime(createdAt)}, nil
+}
+
+func UpdateTag(ctx context.Context, db *sql.DB, id, userID int64, name, color string) (models.Tag, error) {
+	name, color, err := normalizeTag(name, color)
+	if err != nil {
+		return models.Tag{}, err
+	}
+
+	var tag models.Tag
+	err = withTx(ctx, db, func(tx *sql.Tx) error {
+		before, err := scanTag(tx.QueryRowContext(ctx, `SELECT id, user_id, name, color, created_at FROM tags WHERE id = $1 AND user_id = $2 FOR UPDATE;`, id, userID))
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE tags SET name = $1, color = $2 WHERE id = $3;`, name, color, id); err != nil {
+			if isUniqueViolation(err) {
+				return ErrTagNameTaken
+			}
+			return fmt.Errorf("update tag: %w", err)
+		}
+		tag = before
+		tag.Name, tag.Color = name, color
+		return recordAudit(ctx, tx, "tag.update", "tag", id, before, tag)
+	})
+	if err != nil {
+		return models.Tag{}, err
+	}
+	return tag, nil
+}
+
+// DeleteTag removes a tag; its account assignments cascade.
+func DeleteTag(ctx context.Context, db *sql.DB, id, userID int64) error {
+	result, err := db.ExecContext(ctx, `DELETE FROM tags WHERE id = $1 AND user_id = $2;`, id, userID)
+	if err != nil {
+		return fmt.Errorf("delete tag: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SetAccountTags replaces the account's tags with tagIDs. The account and
+// every tag must belong to userID.
+func SetAccountTags(ctx context.Context, db *sql.DB, accountID, userID int64, tagIDs []int64) error {
+	tagIDs = uniqueIDs(tagIDs)
+
+	return withTx(ctx, db, func(tx *sql.Tx) error {
+		if err := lockOwnedAccount(ctx, tx, accountID, userID); err != nil {
+			return err
+		}
+		if err := ensureTagsOwned(ctx, tx, userID, tagIDs); err != nil {
+			return err
+		}
+
+		var before []int64
+		if err := tx.QueryRowContext(
+			ctx,
+			`SELECT COALESCE(array_agg(tag_id ORDER BY tag_id), '{}') FROM account_tags WHERE account_id = $1;`,
+			accountID,
+		).Scan(pq.Array(&before)); err != nil {
+			return fmt.Errorf("load account tags: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM account_tags WHERE account_id = $1;`, accountID); err != nil {
+			return fmt.Errorf("clear account tags: %w", err)
+		}
+
+		for _, tagID := range tagIDs {
+			if _, err := tx.ExecContext(ctx, `INSERT INTO account_tags (account_id, tag_id) VALUES ($1, $2);`, accountID, tagID); err != nil {
+				return fmt.Errorf("insert account tag: %w", err)
+			}
+		}
+
+		after := append([]int64(nil), tagIDs...)
+		sort.Slice(after, func(i, j int) bool { return after[i] < after[j] })
+		return recordAudit(ctx, tx, "account.tags", "account", accountID, map[string]any{"tag_ids": before}, map[string]any{"tag_ids": after})
+	})
+}
+
+// AddAccountTag attaches one tag to an account; attaching it twice is a no-op.
+func AddAccountTag(ctx context.Context, db *sql.DB, accountID, tagID, userID int64) error {
+	return withTx(ctx, db, func(tx *sql.Tx) error {
+		return addAccountTagTx(ctx, tx, accountID, tagID, userID)
+	})
+}
+
+func addAccountTagTx(ctx context.Context, tx *sql.Tx, accountID, tagID, userID int64) error {
+	if err := lockOwnedAccount(ctx, tx, accountID, userID); err != nil {
+		return err
+	}
+	if _, err := GetTag(ctx, tx, tagID, userID); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO account_tags (account_id, tag_id) VALUES ($1, $2) ON CONFLICT DO NOTHING;`,
+		accountID,
+		tagID,
+	)
+	if err != nil {
+		return fmt.Errorf("add account tag: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return nil
+	}
+	return recordAudit(ctx, tx, "account.tag_add", "account", accountID, nil, map[string]any{"tag_id": tagID})
+}
+
+// RemoveAccountTag detaches one tag from an account.
+func RemoveAccountTag(ctx context.Context, db *sql.DB, accountID, tagID, userID int64) error {
+	return withTx(ctx, db, func(tx *sql.Tx) error {
+		return removeAccountTagTx(ctx, tx, accountID, tagID, userID)
+	})
+}
+
+func removeAccountTagTx(ctx context.Context, tx *sql.Tx, accountID, tagID, userID int64) error {
+	if err := lockOwnedAccount(ctx, tx, accountID, userID); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM account_tags WHERE account_id = $1 AND tag_id = $2;`, accountID, tagID)
+	if err != nil {
+		return fmt.Errorf("remove account tag: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return recordAudit(ctx, tx, "account.tag_remove", "account", accountID, map[string]any{"tag_id": tagID}, nil)
+}
+
+// loadAccountTags fills the Tags field of every account with one query.
+func loadAccountTags(ctx context.Context, db querier, accounts []models.Account) error {
+	if len(accounts) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(accounts))
+	index := make(map[int64]int, len(accounts))
+	for i := range accounts {
+		ids[i] = accounts[i].ID
+		index[accounts[i].ID] = i
+		accounts[i].Tags = []models.Tag{}
+	}
+
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT at.account_id, t.id, t.user_id, t.name, t.color, t.created_at
+		FROM account_tags at JOIN tags t ON t.id = at.tag_id
+		WHERE at.account_id = ANY($1) ORDER BY LOWER(t.name) ASC;`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return fmt.Errorf("query account tags: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var accountID int64
+		var tag models.Tag
+		var created string
+		if err := rows.Scan(&accountID, &tag.ID, &tag.UserID, &tag.Name, &tag.Color, &created); err != nil {
+			return fmt.Errorf("scan account tag: %w", err)
+		}
+		tag.CreatedAt = parseSQLiteTime(created)
+		if i, ok := index[accountID]; ok {
+			accounts[i].Tags = append(accounts[i].Tags, tag)
+		}
+	}
+	return rows.Err()
+}
+
+func ensureTagsOwned(ctx context.Context, tx *sql.Tx, userID int64, tagIDs []int64) error {
+	if len(tagIDs) == 0 {
+		return nil
+	}
+	var count int
+	if err := tx.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*) FROM tags WHERE user_id = $1 AND id = ANY($2);`,
+		userID,
+		pq.Array(tagIDs),
+	).Scan(&count); err != nil {
+		return fmt.Errorf("check tags: %w", err)
+	}
+	if count != len(tagIDs) {
+		return fmt.Errorf("%w: unknown tag id", ErrInvalidTag)
+	}
+	return nil
+}
+
+func scanTag(row rowScanner) (models.Tag, error) {
+	var tag models.Tag
+	var created string
+	if err := row.Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.Color, &created); err != nil {
+		return tag, err
+	}
+	tag.CreatedAt = parseSQLiteTime(created)
+	return tag, nil
+}
+
+func normalizeTag(name, color string) (string, string, error) {
+	name = strings.TrimSpace(name)
+	color = strings.TrimSpace(color)
+	if name == "" {
+		return "", "", fmt.Errorf("%w: name is required", ErrInvalidTag)
+	}
+	if len(name) > 64 {
+		return "", "", fmt.Errorf("%w: name must be at most 64 characters", ErrInvalidTag)
+	}
+	if color == "" {
+		color = defaultTagColor
+	}
+	if !tagColorPattern.MatchString(color) {
+		return "", "", fmt.Errorf("%w: color must be a hex value like #ff0000", ErrInvalidTag)
+	}
+	return name, strings.ToLower(color), nil
+}
+
+func uniqueIDs(ids []int64) []int64 {
+	seen := make(map[int64]bool, len(ids))
+	out := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
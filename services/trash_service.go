@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"netflix_central/models"
+)
+
+// TrashConfig controls how long deleted accounts stay restorable and how
+// often the purge job runs.
+type TrashConfig struct {
+	RetentionDays int
+	Interval      time.Duration
+}
+
+// LoadTrashConfig reads TRASH_RETENTION_DAYS and TRASH_PURGE_INTERVAL (a Go
+// duration) from the environment.
+func LoadTrashConfig() TrashConfig {
+	cfg := TrashConfig{RetentionDays: envInt("TRASH_RETENTION_DAYS", 30), Interval: time.Hour}
+	if raw := strings.TrimSpace(os.Getenv("TRASH_PURGE_INTERVAL")); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+			cfg.Interval = d
+		}
+	}
+	return cfg
+}
+
+// ListTrashedAccounts returns the user's deleted accounts, most recently deleted first.
+func ListTrashedAccounts(ctx context.Context, db *sql.DB, userID int64) ([]models.Account, error) {
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT `+accountColumns+` FROM accounts WHERE user_id = $1 AND deleted_at IS NOT NULL ORDER BY deleted_at DESC, id DESC;`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query trash: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := []models.Account{}
+	for rows.Next() {
+		acc, err := scanAccount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan account: %w", err)
+		}
+		accounts = append(accounts, acc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := loadAccountTags(ctx, db, accounts); err != nil {
+		return nil, err
+	}
+	if err := loadAccountSubscriptions(ctx, db, accounts); err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// RestoreAccount takes an account back out of the trash.
+func RestoreAccount(ctx context.Context, db *sql.DB, id, userID int64) (models.Account, error) {
+	err := withTx(ctx, db, func(tx *sql.Tx) error {
+		var deletedAt string
+		if err := tx.QueryRowContext(
+			ctx,
+			`SELECT deleted_at::text FROM accounts WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL FOR UPDATE;`,
+			id,
+			userID,
+		).Scan(&deletedAt); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE accounts SET deleted_at = NULL WHERE id = $1;`, id); err != nil {
+			return fmt.Errorf("restore account: %w", err)
+		}
+		return recordAudit(ctx, tx, "account.restore", "account", id, map[string]any{"deleted_at": deletedAt}, map[string]any{"deleted_at": nil})
+	})
+	if err != nil {
+		return models.Account{}, err
+	}
+	return GetAccount(ctx, db, id, userID)
+}
+
+// RunTrashPurger purges expired trash once immediately and then on every
+// tick until ctx is cancelled.
+func RunTrashPurger(ctx context.Context, db *sql.DB, cfg TrashConfig) {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := PurgeTrash(ctx, db, cfg.RetentionDays, time.Now().UTC()); err != nil {
+			log.Printf("trash purge: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeTrash permanently deletes accounts that have been in the trash for
+// longer than retentionDays, together with their Chrome profile directories.
+func PurgeTrash(ctx context.Context, db *sql.DB, retentionDays int, now time.Time) error {
+	cutoff := now.AddDate(0, 0, -retentionDays).Format(time.RFC3339Nano)
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT id, user_id, chrome_profile FROM accounts WHERE deleted_at IS NOT NULL AND deleted_at < $1;`,
+		cutoff,
+	)
+	if err != nil {
+		return fmt.Errorf("query expired trash: %w", err)
+	}
+
+	type expired struct {
+		id, userID int64
+		profile    string
+	}
+	var accounts []expired
+	for rows.Next() {
+		var e expired
+		if err := rows.Scan(&e.id, &e.userID, &e.profile); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan expired account: %w", err)
+		}
+		accounts = append(accounts, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, e := range accounts {
+		err := purgeAccount(withActorUser(ctx, e.userID, ""), db, e.id, cutoff)
+		if errors.Is(err, sql.ErrNoRows) {
+			// Restored since the listing above.
+			continue
+		}
+		if err != nil {
+			log.Printf("purge account %d: %v", e.id, err)
+			continue
+		}
+		if err := removeProfileDir(e.profile); err != nil {
+			log.Printf("remove profile dir of account %d: %v", e.id, err)
+		}
+	}
+	return nil
+}
+
+// purgeAccount deletes one trashed account and its tabs. The deleted_at check
+// is repeated so an account restored in the meantime is left alone.
+func purgeAccount(ctx context.Context, db *sql.DB, id int64, cutoff string) error {
+	return withTx(ctx, db, func(tx *sql.Tx) error {
+		var profile string
+		if err := tx.QueryRowContext(
+			ctx,
+			`SELECT chrome_profile FROM accounts WHERE id = $1 AND deleted_at IS NOT NULL AND deleted_at < $2 FOR UPDATE;`,
+			id,
+			cutoff,
+		).Scan(&profile); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM tabs WHERE account_id = $1;`, id); err != nil {
+			return fmt.Errorf("delete tabs: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM accounts WHERE id = $1;`, id); err != nil {
+			return fmt.Errorf("delete account: %w", err)
+		}
+
+		return recordAudit(ctx, tx, "account.purge", "account", id, map[string]any{"chrome_profile": profile}, nil)
+	})
+}
+
+func removeProfileDir(profileName string) error {
+	if strings.TrimSpace(profileName) == "" {
+		return nil
+	}
+	dir, err := resolveProfileDir(profileName)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrVersionMismatch means the row changed since the client read it.
+var ErrVersionMismatch = errors.New("modified by someone else, reload and try again")
+
+// querier is satisfied by both *sql.DB and *sql.Tx, for reads that are used
+// inside and outside transactions.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// withTx runs fn inside a transaction. The transaction is committed when fn
+// returns nil and rolled back when it returns an error or panics, so callers
+// never leave a half-applied write behind.
+func withTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// withTxRetry runs withTx again, up to three attempts in total, when the
+// transaction loses a race on a unique constraint such as the tab positions.
+// fn must therefore be safe to run more than once.
+func withTxRetry(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	var err error
+	for attempt := 0; attempt < 3; attempt++ {
+		if err = withTx(ctx, db, fn); err == nil || !isUniqueViolation(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// checkVersion compares the version a client sent in If-Match with the
+// current one. Zero means the client sent no precondition.
+func checkVersion(expected, current int) error {
+	if expected != 0 && expected != current {
+		return fmt.Errorf("%w (version is %d)", ErrVersionMismatch, current)
+	}
+	return nil
+}
+
+// lockAccountVersion locks the account like lockOwnedAccount and checks
+// its version against expected.
+func lockAccountVersion(ctx context.Context, tx *sql.Tx, id, userID int64, expected int) error {
+	var version int
+	if err := tx.QueryRowContext(
+		ctx,
+		`SELECT version FROM accounts WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL FOR UPDATE;`,
+		id,
+		userID,
+	).Scan(&version); err != nil {
+		return err
+	}
+	return checkVersion(expected, version)
+}
+
+// lockOwnedAccount locks the account row for the rest of the transaction and
+// returns sql.ErrNoRows unless it exists, belongs to userID and is not in the
+// trash. Writes touching an account's rows must call it before anything else.
+func lockOwnedAccount(ctx context.Context, tx *sql.Tx, id, userID int64) error {
+	var locked int64
+	return tx.QueryRowContext(
+		ctx,
+		`SELECT id FROM accounts WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL FOR UPDATE;`,
+		id,
+		userID,
+	).Scan(&locked)
+}
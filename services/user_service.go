@@ -1,10 +1,10 @@
 package services
 
 import (
+	"context"
 	"database/sql"
 	"errors"
-
-	"golang.org/x/crypto/bcrypt"
+	"log"
 
 	"netflix_central/database"
 	"netflix_central/models"
@@ -15,20 +15,32 @@ var (
 	ErrUserNotFound       = errors.New("user not found")
 )
 
-func CreateUser(email, password string) (*models.User, error) {
+func CreateUser(ctx context.Context, email, password string) (*models.User, error) {
 	db := database.GetDB()
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
+	if err := LoadPasswordPolicy().Validate(email, password); err != nil {
 		return nil, err
 	}
 
-	res, err := db.Exec("INSERT INTO users (email, password_hash) VALUES ($1, $2)", email, string(hash))
+	hash, err := hashPassword(password)
 	if err != nil {
 		return nil, err
 	}
 
-	id, _ := res.LastInsertId()
+	var id int64
+	if err := withTx(ctx, db, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, "INSERT INTO users (email, password_hash) VALUES ($1, $2) RETURNING id", email, string(hash)).Scan(&id); err != nil {
+			return err
+		}
+		return ensureStarterTemplate(ctx, tx, id)
+	}); err != nil {
+		return nil, err
+	}
+
+	ctx = withActorUser(ctx, id, email)
+	if err := recordAudit(ctx, db, "auth.register", "user", id, nil, map[string]any{"email": email}); err != nil {
+		log.Printf("audit register for user %d: %v", id, err)
+	}
 	return &models.User{ID: id, Email: email, PasswordHash: string(hash)}, nil
 }
 
@@ -45,7 +57,7 @@ func GetUserByEmail(email string) (*models.User, error) {
 	return &u, nil
 }
 
-func ValidateUser(email, password string) (*models.User, error) {
+func ValidateUser(ctx context.Context, email, password string) (*models.User, error) {
 	u, err := GetUserByEmail(email)
 	if err != nil {
 		return nil, err
@@ -53,8 +65,35 @@ func ValidateUser(email, password string) (*models.User, error) {
 	if u == nil {
 		return nil, ErrUserNotFound
 	}
-	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)); err != nil {
+	ctx = withActorUser(ctx, u.ID, u.Email)
+	ok, needsRehash, err := verifyPassword(u.PasswordHash, password)
+	if err != nil || !ok {
+		if err := recordAudit(ctx, database.GetDB(), "auth.login_failed", "user", u.ID, nil, nil); err != nil {
+			log.Printf("audit failed login for user %d: %v", u.ID, err)
+		}
 		return nil, ErrInvalidCredentials
 	}
+	if err := recordAudit(ctx, database.GetDB(), "auth.login", "user", u.ID, nil, nil); err != nil {
+		log.Printf("audit login for user %d: %v", u.ID, err)
+	}
+	if needsRehash {
+		// A failed upgrade must not block the login; the next one retries.
+		if err := rehashPassword(u, password); err != nil {
+			log.Printf("rehash password for user %d: %v", u.ID, err)
+		}
+	}
 	return u, nil
 }
+
+// rehashPassword replaces the stored hash using the current hashing configuration.
+func rehashPassword(u *models.User, password string) error {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	if _, err := database.GetDB().Exec("UPDATE users SET password_hash = $1 WHERE id = $2", hash, u.ID); err != nil {
+		return err
+	}
+	u.PasswordHash = hash
+	return nil
+}
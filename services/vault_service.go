@@ -0,0 +1,497 @@
+package services
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+
+	"netflix_central/models"
+)
+
+var (
+	ErrVaultNotSetUp      = errors.New("vault is not set up")
+	ErrVaultAlreadySetUp  = errors.New("vault is already set up")
+	ErrVaultLocked        = errors.New("vault is locked")
+	ErrVaultDisabled      = errors.New("vault is not enabled for this account")
+	ErrInvalidPassphrase  = errors.New("invalid passphrase")
+	ErrInvalidSecretField = errors.New("invalid secret field")
+	ErrUnlockThrottled    = errors.New("too many unlock attempts")
+)
+
+// SecretFields lists the values that can be kept in an account's vault.
+var SecretFields = []string{"netflix_password", "recovery_phone", "recovery_email", "gmail_password", "gmail_app_password", "notes"}
+
+const (
+	vaultKeyLength       = 32
+	vaultCheckPlaintext  = "netflix-central-vault"
+	defaultUnlockMinutes = 15
+	maxUnlockMinutes     = 240
+
+	// maxUnlockFailures wrong passphrases within unlockFailureWindow block
+	// further unlock attempts until the oldest failure leaves the window.
+	maxUnlockFailures   = 5
+	unlockFailureWindow = 15 * time.Minute
+)
+
+// unlockedKey is a derived vault key held in memory until it expires. The
+// passphrase itself is never kept.
+type unlockedKey struct {
+	key     []byte
+	expires time.Time
+}
+
+var (
+	vaultMu   sync.Mutex
+	vaultKeys = map[int64]unlockedKey{}
+)
+
+// unlockAttempts tracks a user's recent wrong passphrases. Only one unlock
+// per user runs at a time, since each one is an expensive key derivation.
+type unlockAttempts struct {
+	failures []time.Time
+	running  bool
+}
+
+var (
+	unlockMu    sync.Mutex
+	unlockState = map[int64]*unlockAttempts{}
+)
+
+// VaultStatus reports whether the user's vault exists and until when it is unlocked.
+type VaultStatus struct {
+	SetUp         bool       `json:"set_up"`
+	Unlocked      bool       `json:"unlocked"`
+	UnlockedUntil *time.Time `json:"unlocked_until"`
+}
+
+// RevealContext identifies the request that asked to reveal a secret.
+type RevealContext struct {
+	IP        string
+	UserAgent string
+}
+
+func GetVaultStatus(ctx context.Context, db *sql.DB, userID int64) (VaultStatus, error) {
+	var status VaultStatus
+	if err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM user_vaults WHERE user_id = $1);`, userID).Scan(&status.SetUp); err != nil {
+		return status, fmt.Errorf("check vault: %w", err)
+	}
+	if key, ok := currentVaultKey(userID); ok {
+		wipe(key.key)
+		status.Unlocked = true
+		expires := key.expires
+		status.UnlockedUntil = &expires
+	}
+	return status, nil
+}
+
+// SetupVault creates the user's vault for the given passphrase. Only a salt
+// and an encrypted check value are stored, so the passphrase cannot be recovered.
+func SetupVault(ctx context.Context, db *sql.DB, userID int64, passphrase string) error {
+	if len([]rune(passphrase)) < 12 {
+		return fmt.Errorf("%w: passphrase must be at least 12 characters", ErrInvalidPassphrase)
+	}
+
+	cfg := loadHashConfig()
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	key := argon2.IDKey([]byte(passphrase), salt, cfg.ArgonTime, cfg.ArgonMem, cfg.ArgonPar, vaultKeyLength)
+
+	nonce, ciphertext, err := sealSecret(key, []byte(vaultCheckPlaintext), vaultCheckAAD(userID))
+	if err != nil {
+		return err
+	}
+
+	result, err := db.ExecContext(
+		ctx,
+		`INSERT INTO user_vaults (user_id, salt, kdf_time, kdf_memory, kdf_threads, check_nonce, check_ciphertext, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) ON CONFLICT (user_id) DO NOTHING;`,
+		userID,
+		salt,
+		cfg.ArgonTime,
+		cfg.ArgonMem,
+		cfg.ArgonPar,
+		nonce,
+		ciphertext,
+		time.Now().UTC().Format(time.RFC3339Nano),
+	)
+	if err != nil {
+		return fmt.Errorf("create vault: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrVaultAlreadySetUp
+	}
+	return nil
+}
+
+// UnlockVault derives the key from the passphrase and keeps it in memory for
+// the given number of minutes. Attempts are throttled per user, see
+// beginUnlockAttempt.
+func UnlockVault(ctx context.Context, db *sql.DB, userID int64, passphrase string, minutes int) (VaultStatus, error) {
+	if err := beginUnlockAttempt(userID); err != nil {
+		return VaultStatus{}, err
+	}
+	status, err := unlockVault(ctx, db, userID, passphrase, minutes)
+	endUnlockAttempt(userID, err)
+	return status, err
+}
+
+func unlockVault(ctx context.Context, db *sql.DB, userID int64, passphrase string, minutes int) (VaultStatus, error) {
+	var salt, nonce, ciphertext []byte
+	var kdfTime, kdfMemory, kdfThreads int
+	if err := db.QueryRowContext(
+		ctx,
+		`SELECT salt, kdf_time, kdf_memory, kdf_threads, check_nonce, check_ciphertext FROM user_vaults WHERE user_id = $1;`,
+		userID,
+	).Scan(&salt, &kdfTime, &kdfMemory, &kdfThreads, &nonce, &ciphertext); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return VaultStatus{}, ErrVaultNotSetUp
+		}
+		return VaultStatus{}, fmt.Errorf("load vault: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(passphrase), salt, uint32(kdfTime), uint32(kdfMemory), uint8(kdfThreads), vaultKeyLength)
+	plain, err := openSecret(key, nonce, ciphertext, vaultCheckAAD(userID))
+	if err != nil || string(plain) != vaultCheckPlaintext {
+		return VaultStatus{}, ErrInvalidPassphrase
+	}
+
+	if minutes <= 0 {
+		minutes = defaultUnlockMinutes
+	}
+	if minutes > maxUnlockMinutes {
+		minutes = maxUnlockMinutes
+	}
+	expires := time.Now().UTC().Add(time.Duration(minutes) * time.Minute)
+
+	vaultMu.Lock()
+	if old, ok := vaultKeys[userID]; ok {
+		wipe(old.key)
+	}
+	vaultKeys[userID] = unlockedKey{key: key, expires: expires}
+	vaultMu.Unlock()
+
+	return VaultStatus{SetUp: true, Unlocked: true, UnlockedUntil: &expires}, nil
+}
+
+// LockVault forgets the user's derived key immediately.
+func LockVault(userID int64) {
+	vaultMu.Lock()
+	defer vaultMu.Unlock()
+	if key, ok := vaultKeys[userID]; ok {
+		wipe(key.key)
+		delete(vaultKeys, userID)
+	}
+}
+
+// SetAccountVault turns the vault on or off for an account. Disabling it
+// deletes every stored secret of that account.
+func SetAccountVault(ctx context.Context, db *sql.DB, accountID, userID int64, enabled bool) error {
+	return withTx(ctx, db, func(tx *sql.Tx) error {
+		if err := lockOwnedAccount(ctx, tx, accountID, userID); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE accounts SET vault_enabled = $1, version = version + 1 WHERE id = $2;`, enabled, accountID); err != nil {
+			return fmt.Errorf("update vault flag: %w", err)
+		}
+
+		if !enabled {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM account_secrets WHERE account_id = $1;`, accountID); err != nil {
+				return fmt.Errorf("delete secrets: %w", err)
+			}
+		}
+
+		return recordAudit(ctx, tx, "account.vault", "account", accountID, nil, map[string]any{"vault_enabled": enabled})
+	})
+}
+
+// ListSecretFields returns which secrets are stored for the account, without values.
+func ListSecretFields(ctx context.Context, db *sql.DB, accountID int64) ([]models.SecretField, error) {
+	rows, err := db.QueryContext(ctx, `SELECT field, updated_at FROM account_secrets WHERE account_id = $1 ORDER BY field ASC;`, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("query secrets: %w", err)
+	}
+	defer rows.Close()
+
+	fields := []models.SecretField{}
+	for rows.Next() {
+		var f models.SecretField
+		var updated string
+		if err := rows.Scan(&f.Field, &updated); err != nil {
+			return nil, fmt.Errorf("scan secret: %w", err)
+		}
+		f.UpdatedAt = parseSQLiteTime(updated)
+		fields = append(fields, f)
+	}
+	return fields, rows.Err()
+}
+
+// WriteSecret encrypts and stores one secret field. The vault must be unlocked.
+func WriteSecret(ctx context.Context, db *sql.DB, account models.Account, field, value string) error {
+	field, err := normalizeSecretField(field)
+	if err != nil {
+		return err
+	}
+	if !account.VaultEnabled {
+		return ErrVaultDisabled
+	}
+	key, ok := currentVaultKey(account.UserID)
+	if !ok {
+		return ErrVaultLocked
+	}
+
+	nonce, ciphertext, err := sealSecret(key.key, []byte(value), secretAAD(account.ID, field))
+	wipe(key.key)
+	if err != nil {
+		return err
+	}
+
+	return withTx(ctx, db, func(tx *sql.Tx) error {
+		if err := lockOwnedAccount(ctx, tx, account.ID, account.UserID); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO account_secrets (account_id, field, nonce, ciphertext, updated_at) VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (account_id, field) DO UPDATE SET nonce = EXCLUDED.nonce, ciphertext = EXCLUDED.ciphertext, updated_at = EXCLUDED.updated_at;`,
+			account.ID,
+			field,
+			nonce,
+			ciphertext,
+			time.Now().UTC().Format(time.RFC3339Nano),
+		); err != nil {
+			return fmt.Errorf("save secret: %w", err)
+		}
+		// Only the field name is logged; the value never reaches the audit log.
+		return recordAudit(ctx, tx, "secret.write", "account", account.ID, nil, map[string]any{"field": field})
+	})
+}
+
+// RevealSecret decrypts one secret field and records the reveal.
+func RevealSecret(ctx context.Context, db *sql.DB, account models.Account, field string, rc RevealContext) (string, error) {
+	field, err := normalizeSecretField(field)
+	if err != nil {
+		return "", err
+	}
+	if !account.VaultEnabled {
+		return "", ErrVaultDisabled
+	}
+	key, ok := currentVaultKey(account.UserID)
+	if !ok {
+		return "", ErrVaultLocked
+	}
+	defer wipe(key.key)
+
+	var nonce, ciphertext []byte
+	if err := db.QueryRowContext(
+		ctx,
+		`SELECT nonce, ciphertext FROM account_secrets WHERE account_id = $1 AND field = $2;`,
+		account.ID,
+		field,
+	).Scan(&nonce, &ciphertext); err != nil {
+		return "", err
+	}
+
+	plain, err := openSecret(key.key, nonce, ciphertext, secretAAD(account.ID, field))
+	if err != nil {
+		return "", fmt.Errorf("decrypt secret: %w", err)
+	}
+
+	// The value is only handed out once both log entries are stored.
+	err = withTx(ctx, db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO secret_reveals (user_id, account_id, field, ip, user_agent, revealed_at) VALUES ($1, $2, $3, $4, $5, $6);`,
+			account.UserID,
+			account.ID,
+			field,
+			rc.IP,
+			rc.UserAgent,
+			time.Now().UTC().Format(time.RFC3339Nano),
+		); err != nil {
+			return fmt.Errorf("record reveal: %w", err)
+		}
+		return recordAudit(ctx, tx, "secret.reveal", "account", account.ID, nil, map[string]any{"field": field})
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return string(plain), nil
+}
+
+func DeleteSecret(ctx context.Context, db *sql.DB, accountID, userID int64, field string) error {
+	field, err := normalizeSecretField(field)
+	if err != nil {
+		return err
+	}
+	return withTx(ctx, db, func(tx *sql.Tx) error {
+		if err := lockOwnedAccount(ctx, tx, accountID, userID); err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, `DELETE FROM account_secrets WHERE account_id = $1 AND field = $2;`, accountID, field)
+		if err != nil {
+			return fmt.Errorf("delete secret: %w", err)
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return sql.ErrNoRows
+		}
+		return recordAudit(ctx, tx, "secret.delete", "account", accountID, map[string]any{"field": field}, nil)
+	})
+}
+
+// ListSecretReveals returns the reveal log of an account, newest first.
+func ListSecretReveals(ctx context.Context, db *sql.DB, accountID int64) ([]models.SecretReveal, error) {
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT id, user_id, account_id, field, ip, user_agent, revealed_at FROM secret_reveals WHERE account_id = $1 ORDER BY revealed_at DESC, id DESC;`,
+		accountID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query reveals: %w", err)
+	}
+	defer rows.Close()
+
+	reveals := []models.SecretReveal{}
+	for rows.Next() {
+		var r models.SecretReveal
+		var revealed string
+		if err := rows.Scan(&r.ID, &r.UserID, &r.AccountID, &r.Field, &r.IP, &r.UserAgent, &revealed); err != nil {
+			return nil, fmt.Errorf("scan reveal: %w", err)
+		}
+		r.RevealedAt = parseSQLiteTime(revealed)
+		reveals = append(reveals, r)
+	}
+	return reveals, rows.Err()
+}
+
+// currentVaultKey returns a copy of the user's unlocked key. The cached key
+// is wiped when the vault locks or expires, so callers get their own bytes
+// and should wipe them once done.
+func currentVaultKey(userID int64) (unlockedKey, bool) {
+	vaultMu.Lock()
+	defer vaultMu.Unlock()
+	key, ok := vaultKeys[userID]
+	if !ok {
+		return unlockedKey{}, false
+	}
+	if time.Now().UTC().After(key.expires) {
+		wipe(key.key)
+		delete(vaultKeys, userID)
+		return unlockedKey{}, false
+	}
+	return unlockedKey{key: append([]byte(nil), key.key...), expires: key.expires}, true
+}
+
+// beginUnlockAttempt reserves the user's unlock slot. It fails while another
+// unlock is running or after maxUnlockFailures recent wrong passphrases.
+func beginUnlockAttempt(userID int64) error {
+	unlockMu.Lock()
+	defer unlockMu.Unlock()
+	state, ok := unlockState[userID]
+	if !ok {
+		state = &unlockAttempts{}
+		unlockState[userID] = state
+	}
+	now := time.Now()
+	recent := state.failures[:0]
+	for _, at := range state.failures {
+		if now.Sub(at) < unlockFailureWindow {
+			recent = append(recent, at)
+		}
+	}
+	state.failures = recent
+
+	if state.running {
+		return fmt.Errorf("%w: another unlock is in progress", ErrUnlockThrottled)
+	}
+	if len(state.failures) >= maxUnlockFailures {
+		wait := unlockFailureWindow - now.Sub(state.failures[0])
+		return fmt.Errorf("%w: try again in %s", ErrUnlockThrottled, wait.Round(time.Second))
+	}
+	state.running = true
+	return nil
+}
+
+// endUnlockAttempt releases the slot taken by beginUnlockAttempt and
+// counts a wrong passphrase. A successful unlock clears the user's failures.
+func endUnlockAttempt(userID int64, err error) {
+	unlockMu.Lock()
+	defer unlockMu.Unlock()
+	state := unlockState[userID]
+	state.running = false
+	switch {
+	case errors.Is(err, ErrInvalidPassphrase):
+		state.failures = append(state.failures, time.Now())
+	case err == nil:
+		state.failures = nil
+	}
+	if len(state.failures) == 0 {
+		delete(unlockState, userID)
+	}
+}
+
+func normalizeSecretField(field string) (string, error) {
+	field = strings.ToLower(strings.TrimSpace(field))
+	for _, f := range SecretFields {
+		if f == field {
+			return field, nil
+		}
+	}
+	return "", fmt.Errorf("%w: field must be one of %s", ErrInvalidSecretField, strings.Join(SecretFields, ", "))
+}
+
+// secretAAD binds a ciphertext to its account and field so rows cannot be swapped.
+func secretAAD(accountID int64, field string) []byte {
+	return []byte(fmt.Sprintf("account:%d:%s", accountID, field))
+}
+
+func vaultCheckAAD(userID int64) []byte {
+	return []byte(fmt.Sprintf("vault-check:%d", userID))
+}
+
+func sealSecret(key, plaintext, aad []byte) ([]byte, []byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	return nonce, gcm.Seal(nil, nonce, plaintext, aad), nil
+}
+
+func openSecret(key, nonce, ciphertext, aad []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Open(nil, nonce, ciphertext, aad)
+}
+
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
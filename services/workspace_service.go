@@ -0,0 +1,910 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"netflix_central/models"
+)
+
+var ErrInvalidArchive = errors.New("invalid workspace archive")
+
+// Conflict strategies for accounts whose email already exists in the target workspace.
+const (
+	ConflictSkip      = "skip"
+	ConflictOverwrite = "overwrite"
+	ConflictRename    = "rename"
+)
+
+const (
+	workspaceManifestName = "manifest.json"
+	workspaceProfilesDir  = "profiles/"
+)
+
+// ExportWorkspace writes a zip archive with a manifest of the user's
+// accounts with their tabs, tab groups and variables, tags and library tabs. With includeProfiles the Chrome profile directory
+// of every account is added under profiles/<name>/; Chrome should be closed
+// first, files it keeps locked are skipped.
+func ExportWorkspace(ctx context.Context, db *sql.DB, userID int64, w io.Writer, includeProfiles bool) error {
+	manifest, err := buildWorkspaceManifest(ctx, db, userID)
+	if err != nil {
+		return err
+	}
+	manifest.Profiles = includeProfiles
+
+	zw := zip.NewWriter(w)
+	mf, err := zw.Create(workspaceManifestName)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(mf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+
+	if includeProfiles {
+		for _, acc := range manifest.Accounts {
+			if err := addProfileToArchive(zw, acc.ChromeProfile); err != nil {
+				return fmt.Errorf("archive profile %s: %w", acc.ChromeProfile, err)
+			}
+		}
+	}
+	return zw.Close()
+}
+
+func buildWorkspaceManifest(ctx context.Context, db *sql.DB, userID int64) (models.WorkspaceManifest, error) {
+	manifest := models.WorkspaceManifest{
+		Version:     models.WorkspaceManifestVersion,
+		ExportedAt:  time.Now().UTC(),
+		Tags:        []models.WorkspaceTag{},
+		LibraryTabs: []models.WorkspaceLibraryTab{},
+		Accounts:    []models.WorkspaceAccount{},
+	}
+
+	tags, err := ListTags(ctx, db, userID)
+	if err != nil {
+		return manifest, err
+	}
+	for _, tag := range tags {
+		manifest.Tags = append(manifest.Tags, models.WorkspaceTag{ID: tag.ID, Name: tag.Name, Color: tag.Color})
+	}
+	libraryTabs, err := ListLibraryTabs(ctx, db, userID)
+	if err != nil {
+		return manifest, err
+	}
+	for _, tab := range libraryTabs {
+		manifest.LibraryTabs = append(manifest.LibraryTabs, models.WorkspaceLibraryTab{ID: tab.ID, Title: tab.Title, URL: tab.URL})
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT `+accountColumns+` FROM accounts WHERE user_id = $1 AND deleted_at IS NULL ORDER BY id ASC;`, userID)
+	if err != nil {
+		return manifest, fmt.Errorf("query accounts: %w", err)
+	}
+	var accounts []models.Account
+	for rows.Next() {
+		acc, err := scanAccount(rows)
+		if err != nil {
+			rows.Close()
+			return manifest, fmt.Errorf("scan account: %w", err)
+		}
+		accounts = append(accounts, acc)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return manifest, err
+	}
+	if err := loadAccountTags(ctx, db, accounts); err != nil {
+		return manifest, err
+	}
+
+	ids := make([]int64, len(accounts))
+	index := make(map[int64]int, len(accounts))
+	for i, acc := range accounts {
+		ids[i] = acc.ID
+		index[acc.ID] = i
+		entry := models.WorkspaceAccount{
+			ID:            acc.ID,
+			Label:         acc.Label,
+			NetflixEmail:  acc.NetflixEmail,
+			Status:        acc.Status,
+			ChromeProfile: acc.ChromeProfile,
+			CreatedAt:     acc.CreatedAt,
+			TagIDs:        []int64{},
+			Variables:     map[string]string{},
+			TabGroups:     []models.WorkspaceTabGroup{},
+			Tabs:          []models.WorkspaceTab{},
+		}
+		for _, tag := range acc.Tags {
+			entry.TagIDs = append(entry.TagIDs, tag.ID)
+		}
+		manifest.Accounts = append(manifest.Accounts, entry)
+	}
+
+	if err := addWorkspaceTabGroups(ctx, db, manifest.Accounts, ids, index); err != nil {
+		return manifest, err
+	}
+	if err := addWorkspaceVariables(ctx, db, manifest.Accounts, ids, index); err != nil {
+		return manifest, err
+	}
+
+	tabRows, err := db.QueryContext(
+		ctx,
+		tabSelect+` WHERE t.account_id = ANY($1) ORDER BY t.account_id ASC, t.position ASC, t.id ASC;`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return manifest, fmt.Errorf("query tabs: %w", err)
+	}
+	defer tabRows.Close()
+	for tabRows.Next() {
+		tab, err := scanTab(tabRows)
+		if err != nil {
+			return manifest, fmt.Errorf("scan tab: %w", err)
+		}
+		if i, ok := index[tab.AccountID]; ok {
+			manifest.Accounts[i].Tabs = append(manifest.Accounts[i].Tabs, models.WorkspaceTab{
+				Title:            tab.Title,
+				URL:              tab.URL,
+				Position:         tab.Position,
+				LibraryTabID:     tab.LibraryTabID,
+				TitleOverridden:  tab.TitleOverridden,
+				URLOverridden:    tab.URLOverridden,
+				GroupID:          tab.GroupID,
+				Pinned:           tab.Pinned,
+				Enabled:          tab.Enabled,
+				OpenInBackground: tab.OpenInBackground,
+				OpenOn:           tab.OpenOn,
+			})
+		}
+	}
+	return manifest, tabRows.Err()
+}
+
+func addWorkspaceTabGroups(ctx context.Context, db *sql.DB, accounts []models.WorkspaceAccount, ids []int64, index map[int64]int) error {
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT id, account_id, name, color, collapsed, position FROM tab_groups WHERE account_id = ANY($1) ORDER BY account_id ASC, position ASC, id ASC;`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return fmt.Errorf("query tab groups: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		group, err := scanTabGroup(rows)
+		if err != nil {
+			return fmt.Errorf("scan tab group: %w", err)
+		}
+		if i, ok := index[group.AccountID]; ok {
+			accounts[i].TabGroups = append(accounts[i].TabGroups, models.WorkspaceTabGroup{
+				ID:        group.ID,
+				Name:      group.Name,
+				Color:     group.Color,
+				Collapsed: group.Collapsed,
+				Position:  group.Position,
+			})
+		}
+	}
+	return rows.Err()
+}
+
+func addWorkspaceVariables(ctx context.Context, db *sql.DB, accounts []models.WorkspaceAccount, ids []int64, index map[int64]int) error {
+	rows, err := db.QueryContext(ctx, `SELECT account_id, name, value FROM account_variables WHERE account_id = ANY($1);`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("query account variables: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var accountID int64
+		var name, value string
+		if err := rows.Scan(&accountID, &name, &value); err != nil {
+			return fmt.Errorf("scan account variable: %w", err)
+		}
+		if i, ok := index[accountID]; ok {
+			accounts[i].Variables[name] = value
+		}
+	}
+	return rows.Err()
+}
+
+func addProfileToArchive(zw *zip.Writer, profileName string) error {
+	dir, err := resolveProfileDir(profileName)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Sockets, symlinks and other special files (Chrome's Singleton*
+		// lock files) are not part of the profile data.
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			log.Printf("export profile %s: skipping %s: %v", profileName, rel, err)
+			return nil
+		}
+		defer f.Close()
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = workspaceProfilesDir + profileName + "/" + filepath.ToSlash(rel)
+		header.Method = zip.Deflate
+
+		out, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, f)
+		return err
+	})
+}
+
+// profileRestore is a profile directory to unpack once the import committed.
+type profileRestore struct {
+	source, target string
+	replace        bool
+}
+
+// ImportWorkspace restores an archive written by ExportWorkspace into the
+// user's workspace. Tags are matched by name and accounts by email; strategy
+// decides what happens to accounts that already exist. All rows are written
+// in one transaction and profile directories are unpacked afterwards.
+func ImportWorkspace(ctx context.Context, db *sql.DB, userID int64, r io.ReaderAt, size int64, strategy string) (models.WorkspaceImportResult, error) {
+	result := models.WorkspaceImportResult{Strategy: strategy, Accounts: []models.WorkspaceImportItem{}}
+	switch strategy {
+	case ConflictSkip, ConflictOverwrite, ConflictRename:
+	default:
+		return result, fmt.Errorf("%w: strategy must be one of skip, overwrite, rename", ErrInvalidArchive)
+	}
+
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return result, fmt.Errorf("%w: not a zip file", ErrInvalidArchive)
+	}
+	manifest, profiles, err := readWorkspaceArchive(zr)
+	if err != nil {
+		return result, err
+	}
+
+	var restores []profileRestore
+	err = withTx(ctx, db, func(tx *sql.Tx) error {
+		tagIDs, err := importWorkspaceTags(ctx, tx, userID, manifest.Tags, &result)
+		if err != nil {
+			return err
+		}
+		ids := workspaceIDs{tags: tagIDs}
+		if ids.libraryTabs, err = importWorkspaceLibraryTabs(ctx, tx, userID, manifest.LibraryTabs, &result); err != nil {
+			return err
+		}
+
+		existing, err := existingAccountsByEmail(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		usedProfiles, err := usedProfileNames(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		for _, src := range manifest.Accounts {
+			item := models.WorkspaceImportItem{SourceID: src.ID, Label: src.Label}
+			target, conflict := existing[strings.ToLower(src.NetflixEmail)]
+
+			switch {
+			case conflict && strategy == ConflictSkip:
+				item.AccountID, item.Action = target.ID, "skipped"
+				result.Skipped++
+
+			case conflict && strategy == ConflictOverwrite:
+				if err := overwriteAccountTx(ctx, tx, target, src, ids); err != nil {
+					return fmt.Errorf("account %d: %w", src.ID, err)
+				}
+				item.AccountID, item.Action = target.ID, "overwritten"
+				result.Overwritten++
+				if profiles[src.ChromeProfile] {
+					restores = append(restores, profileRestore{source: src.ChromeProfile, target: target.ChromeProfile, replace: true})
+				}
+
+			default:
+				acc := models.Account{
+					UserID:        userID,
+					Label:         src.Label,
+					NetflixEmail:  src.NetflixEmail,
+					Status:        src.Status,
+					ChromeProfile: src.ChromeProfile,
+					CreatedAt:     src.CreatedAt,
+				}
+				if conflict {
+					acc.Label = src.Label + " (imported)"
+				}
+				if usedProfiles[acc.ChromeProfile] || profileDirExists(acc.ChromeProfile) {
+					acc.ChromeProfile = ""
+				}
+
+				created, err := insertAccountTx(ctx, tx, acc)
+				if err != nil {
+					return fmt.Errorf("account %d: %w", src.ID, err)
+				}
+				usedProfiles[created.ChromeProfile] = true
+				if err := replaceAccountContentsTx(ctx, tx, created.ID, src, ids); err != nil {
+					return fmt.Errorf("account %d: %w", src.ID, err)
+				}
+
+				item.AccountID, item.Label = created.ID, created.Label
+				if conflict {
+					item.Action = "renamed"
+					result.Renamed++
+				} else {
+					item.Action = "created"
+					result.Created++
+				}
+				if profiles[src.ChromeProfile] {
+					restores = append(restores, profileRestore{source: src.ChromeProfile, target: created.ChromeProfile})
+				}
+			}
+			result.Accounts = append(result.Accounts, item)
+		}
+
+		return recordAudit(ctx, tx, "workspace.import", "user", userID, nil, map[string]any{
+			"strategy":    strategy,
+			"created":     result.Created,
+			"overwritten": result.Overwritten,
+			"renamed":     result.Renamed,
+			"skipped":     result.Skipped,
+		})
+	})
+	if err != nil {
+		return models.WorkspaceImportResult{Strategy: strategy, Accounts: []models.WorkspaceImportItem{}}, err
+	}
+
+	// The rows are committed at this point; a profile that fails to unpack
+	// only costs the saved browser session, so it is logged and skipped.
+	limits := loadExtractLimits()
+	for _, restore := range restores {
+		if err := restoreProfile(zr, restore, limits); err != nil {
+			log.Printf("restore profile %s: %v", restore.target, err)
+			continue
+		}
+		result.ProfilesRestored++
+	}
+	return result, nil
+}
+
+// readWorkspaceArchive decodes and validates the manifest and returns the
+// names of the profiles contained in the archive.
+func readWorkspaceArchive(zr *zip.Reader) (models.WorkspaceManifest, map[string]bool, error) {
+	var manifest models.WorkspaceManifest
+	profiles := map[string]bool{}
+	found := false
+
+	for _, f := range zr.File {
+		if f.Name == workspaceManifestName {
+			rc, err := f.Open()
+			if err != nil {
+				return manifest, nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+			}
+			err = json.NewDecoder(rc).Decode(&manifest)
+			rc.Close()
+			if err != nil {
+				return manifest, nil, fmt.Errorf("%w: manifest is not valid JSON", ErrInvalidArchive)
+			}
+			found = true
+			continue
+		}
+		if rest, ok := strings.CutPrefix(f.Name, workspaceProfilesDir); ok {
+			if name, _, ok := strings.Cut(rest, "/"); ok && name != "" {
+				profiles[name] = true
+			}
+		}
+	}
+
+	if !found {
+		return manifest, nil, fmt.Errorf("%w: %s is missing", ErrInvalidArchive, workspaceManifestName)
+	}
+	switch manifest.Version {
+	case 1:
+		upgradeWorkspaceManifestV1(&manifest)
+	case models.WorkspaceManifestVersion:
+	default:
+		return manifest, nil, fmt.Errorf("%w: unsupported manifest version %d", ErrInvalidArchive, manifest.Version)
+	}
+
+	libraryTabs := make(map[int64]bool, len(manifest.LibraryTabs))
+	for i, tab := range manifest.LibraryTabs {
+		title, link, err := normalizeTabInput(tab.Title, tab.URL)
+		if err != nil {
+			return manifest, nil, fmt.Errorf("%w: library tab %d: %v", ErrInvalidArchive, tab.ID, err)
+		}
+		if libraryTabs[tab.ID] {
+			return manifest, nil, fmt.Errorf("%w: library tab id %d appears twice", ErrInvalidArchive, tab.ID)
+		}
+		libraryTabs[tab.ID] = true
+		manifest.LibraryTabs[i].Title, manifest.LibraryTabs[i].URL = title, link
+	}
+
+	seen := map[string]bool{}
+	for i, acc := range manifest.Accounts {
+		acc.Label = strings.TrimSpace(acc.Label)
+		acc.NetflixEmail = strings.TrimSpace(acc.NetflixEmail)
+		acc.Status = normalizeStatus(acc.Status)
+		if acc.Label == "" || acc.NetflixEmail == "" {
+			return manifest, nil, fmt.Errorf("%w: account %d needs a label and email", ErrInvalidArchive, acc.ID)
+		}
+		if acc.Status == "" {
+			return manifest, nil, fmt.Errorf("%w: account %d has an unknown status", ErrInvalidArchive, acc.ID)
+		}
+		if !validProfileName(acc.ChromeProfile) {
+			acc.ChromeProfile = ""
+		}
+		vars, err := normalizeAccountVariables(acc.Variables)
+		if err != nil {
+			return manifest, nil, fmt.Errorf("%w: account %d: %v", ErrInvalidArchive, acc.ID, err)
+		}
+		acc.Variables = vars
+		groups := make(map[int64]bool, len(acc.TabGroups))
+		for j, group := range acc.TabGroups {
+			in, err := normalizeTabGroup(TabGroupInput{Name: group.Name, Color: group.Color, Collapsed: group.Collapsed})
+			if err != nil {
+				return manifest, nil, fmt.Errorf("%w: account %d, tab group %d: %v", ErrInvalidArchive, acc.ID, group.ID, err)
+			}
+			if groups[group.ID] {
+				return manifest, nil, fmt.Errorf("%w: account %d: tab group id %d appears twice", ErrInvalidArchive, acc.ID, group.ID)
+			}
+			groups[group.ID] = true
+			acc.TabGroups[j].Name, acc.TabGroups[j].Color = in.Name, in.Color
+		}
+		for j, tab := range acc.Tabs {
+			title, link, err := normalizeTabInput(tab.Title, tab.URL)
+			if err != nil {
+				return manifest, nil, fmt.Errorf("%w: account %d, tab %d: %v", ErrInvalidArchive, acc.ID, j+1, err)
+			}
+			acc.Tabs[j].Title, acc.Tabs[j].URL = title, link
+			if name, ok := undefinedTabVariable(link, acc.Variables); ok {
+				return manifest, nil, fmt.Errorf("%w: account %d, tab %d: variable {{%s}} is not defined", ErrInvalidArchive, acc.ID, j+1, name)
+			}
+			if err := (TabOptions{OpenOn: tab.OpenOn}).apply(&models.Tab{}); err != nil || tab.OpenOn == "" {
+				return manifest, nil, fmt.Errorf("%w: account %d, tab %d: invalid open_on %q", ErrInvalidArchive, acc.ID, j+1, tab.OpenOn)
+			}
+			if tab.GroupID != nil && !groups[*tab.GroupID] {
+				return manifest, nil, fmt.Errorf("%w: account %d, tab %d: unknown tab group id %d", ErrInvalidArchive, acc.ID, j+1, *tab.GroupID)
+			}
+			if tab.LibraryTabID != nil && !libraryTabs[*tab.LibraryTabID] {
+				return manifest, nil, fmt.Errorf("%w: account %d, tab %d: unknown library tab id %d", ErrInvalidArchive, acc.ID, j+1, *tab.LibraryTabID)
+			}
+		}
+		key := strings.ToLower(acc.NetflixEmail)
+		if seen[key] {
+			return manifest, nil, fmt.Errorf("%w: email %s appears twice", ErrInvalidArchive, acc.NetflixEmail)
+		}
+		seen[key] = true
+		manifest.Accounts[i] = acc
+	}
+	return manifest, profiles, nil
+}
+
+// upgradeWorkspaceManifestV1 fills in what a version 1 manifest could not
+// hold: its tabs were all enabled and opened on every launch, and it had no
+// library tabs, groups or variables.
+func upgradeWorkspaceManifestV1(manifest *models.WorkspaceManifest) {
+	manifest.LibraryTabs = nil
+	for i := range manifest.Accounts {
+		acc := &manifest.Accounts[i]
+		acc.Variables, acc.TabGroups = nil, nil
+		for j := range acc.Tabs {
+			tab := &acc.Tabs[j]
+			tab.Enabled, tab.OpenOn = true, models.TabOpenAlways
+			tab.LibraryTabID, tab.GroupID = nil, nil
+			tab.TitleOverridden, tab.URLOverridden = false, false
+		}
+	}
+}
+
+// workspaceIDs maps ids of the exporting instance to the importing user's.
+type workspaceIDs struct {
+	tags        map[int64]int64
+	libraryTabs map[int64]int64
+}
+
+// importWorkspaceLibraryTabs maps the archive's library tabs to the user's,
+// reusing one with the same title and URL and creating the others.
+func importWorkspaceLibraryTabs(ctx context.Context, tx *sql.Tx, userID int64, tabs []models.WorkspaceLibraryTab, result *models.WorkspaceImportResult) (map[int64]int64, error) {
+	ids := make(map[int64]int64, len(tabs))
+	for _, tab := range tabs {
+		var id int64
+		err := tx.QueryRowContext(
+			ctx,
+			`SELECT id FROM library_tabs WHERE user_id = $1 AND title = $2 AND url = $3 ORDER BY id ASC LIMIT 1;`,
+			userID,
+			tab.Title,
+			tab.URL,
+		).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			now := time.Now().UTC().Format(time.RFC3339Nano)
+			err = tx.QueryRowContext(
+				ctx,
+				`INSERT INTO library_tabs (user_id, title, url, created_at, updated_at) VALUES ($1, $2, $3, $4, $4) RETURNING id;`,
+				userID,
+				tab.Title,
+				tab.URL,
+				now,
+			).Scan(&id)
+			if err == nil {
+				result.LibraryTabsCreated++
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("import library tab %q: %w", tab.Title, err)
+		}
+		ids[tab.ID] = id
+	}
+	return ids, nil
+}
+
+// importWorkspaceTags maps the archive's tag ids to tags of the user,
+// creating the ones missing by name.
+func importWorkspaceTags(ctx context.Context, tx *sql.Tx, userID int64, tags []models.WorkspaceTag, result *models.WorkspaceImportResult) (map[int64]int64, error) {
+	byName, err := tagIDsByName(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[int64]int64, len(tags))
+	for _, tag := range tags {
+		name, color, err := normalizeTag(tag.Name, tag.Color)
+		if err != nil {
+			return nil, fmt.Errorf("%w: tag %d: %v", ErrInvalidArchive, tag.ID, err)
+		}
+		if id, ok := byName[strings.ToLower(name)]; ok {
+			ids[tag.ID] = id
+			continue
+		}
+
+		var id int64
+		if err := tx.QueryRowContext(
+			ctx,
+			`INSERT INTO tags (user_id, name, color, created_at) VALUES ($1, $2, $3, $4) RETURNING id;`,
+			userID,
+			name,
+			color,
+			time.Now().UTC().Format(time.RFC3339Nano),
+		).Scan(&id); err != nil {
+			return nil, fmt.Errorf("create tag %q: %w", name, err)
+		}
+		byName[strings.ToLower(name)] = id
+		ids[tag.ID] = id
+		result.TagsCreated++
+	}
+	return ids, nil
+}
+
+func existingAccountsByEmail(ctx context.Context, tx *sql.Tx, userID int64) (map[string]models.Account, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT `+accountColumns+` FROM accounts WHERE user_id = $1 AND deleted_at IS NULL ORDER BY id ASC;`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("query accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := map[string]models.Account{}
+	for rows.Next() {
+		acc, err := scanAccount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan account: %w", err)
+		}
+		key := strings.ToLower(acc.NetflixEmail)
+		if _, ok := accounts[key]; !ok {
+			accounts[key] = acc
+		}
+	}
+	return accounts, rows.Err()
+}
+
+// usedProfileNames returns every Chrome profile name in use on this
+// instance, by any user, trashed accounts included.
+func usedProfileNames(ctx context.Context, tx *sql.Tx) (map[string]bool, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT chrome_profile FROM accounts;`)
+	if err != nil {
+		return nil, fmt.Errorf("query profile names: %w", err)
+	}
+	defer rows.Close()
+
+	names := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scan profile name: %w", err)
+		}
+		names[name] = true
+	}
+	return names, rows.Err()
+}
+
+// overwriteAccountTx replaces label, status and contents of an existing
+// account with the archived ones. The status is set directly, without the
+// transition rules, since this restores a known state.
+func overwriteAccountTx(ctx context.Context, tx *sql.Tx, target models.Account, src models.WorkspaceAccount, ids workspaceIDs) error {
+	if err := lockOwnedAccount(ctx, tx, target.ID, target.UserID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE accounts SET label = $1, status = $2, version = version + 1 WHERE id = $3;`, src.Label, src.Status, target.ID); err != nil {
+		return fmt.Errorf("update account: %w", err)
+	}
+	if target.Status != src.Status {
+		if err := recordStatusHistory(ctx, tx, target.ID, target.UserID, target.Status, src.Status, "workspace import"); err != nil {
+			return err
+		}
+	}
+	if err := replaceAccountContentsTx(ctx, tx, target.ID, src, ids); err != nil {
+		return err
+	}
+
+	after := target
+	after.Label, after.Status = src.Label, src.Status
+	return recordAudit(ctx, tx, "account.overwrite", "account", target.ID, target, after)
+}
+
+// replaceAccountContentsTx swaps the account's tabs, tab groups, variables
+// and tags for the archived ones.
+func replaceAccountContentsTx(ctx context.Context, tx *sql.Tx, accountID int64, src models.WorkspaceAccount, ids workspaceIDs) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM tabs WHERE account_id = $1;`, accountID); err != nil {
+		return fmt.Errorf("clear tabs: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM tab_groups WHERE account_id = $1;`, accountID); err != nil {
+		return fmt.Errorf("clear tab groups: %w", err)
+	}
+
+	groups := append([]models.WorkspaceTabGroup(nil), src.TabGroups...)
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].Position < groups[j].Position })
+	groupIDs := make(map[int64]int64, len(groups))
+	for i, group := range groups {
+		var id int64
+		if err := tx.QueryRowContext(
+			ctx,
+			`INSERT INTO tab_groups (account_id, name, color, collapsed, position) VALUES ($1, $2, $3, $4, $5) RETURNING id;`,
+			accountID,
+			group.Name,
+			group.Color,
+			group.Collapsed,
+			i+1,
+		).Scan(&id); err != nil {
+			return fmt.Errorf("insert tab group: %w", err)
+		}
+		groupIDs[group.ID] = id
+	}
+
+	tabs := append([]models.WorkspaceTab(nil), src.Tabs...)
+	sort.SliceStable(tabs, func(i, j int) bool { return tabs[i].Position < tabs[j].Position })
+	for i, tab := range tabs {
+		// A linked tab stores only what it overrides, see library_tabs.
+		title, link := &tab.Title, &tab.URL
+		var libraryTabID, groupID *int64
+		if tab.LibraryTabID != nil {
+			id := ids.libraryTabs[*tab.LibraryTabID]
+			libraryTabID = &id
+			if !tab.TitleOverridden {
+				title = nil
+			}
+			if !tab.URLOverridden {
+				link = nil
+			}
+		}
+		if tab.GroupID != nil {
+			id := groupIDs[*tab.GroupID]
+			groupID = &id
+		}
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO tabs (account_id, title, url, library_tab_id, group_id, pinned, enabled, open_in_background, open_on, position)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);`,
+			accountID,
+			title,
+			link,
+			libraryTabID,
+			groupID,
+			tab.Pinned,
+			tab.Enabled,
+			tab.OpenInBackground,
+			tab.OpenOn,
+			i+1,
+		); err != nil {
+			return fmt.Errorf("insert tab: %w", err)
+		}
+	}
+	if err := normalizeTabOrder(ctx, tx, accountID); err != nil {
+		return err
+	}
+	if err := replaceAccountVariablesTx(ctx, tx, accountID, src.Variables); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM account_tags WHERE account_id = $1;`, accountID); err != nil {
+		return fmt.Errorf("clear account tags: %w", err)
+	}
+	for _, srcID := range src.TagIDs {
+		tagID, ok := ids.tags[srcID]
+		if !ok {
+			return fmt.Errorf("%w: unknown tag id %d", ErrInvalidArchive, srcID)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO account_tags (account_id, tag_id) VALUES ($1, $2) ON CONFLICT DO NOTHING;`, accountID, tagID); err != nil {
+			return fmt.Errorf("insert account tag: %w", err)
+		}
+	}
+	return nil
+}
+
+// extractLimits caps what a workspace import may write to disk, so a
+// crafted archive cannot fill it: perFile bytes per file and remaining bytes
+// for all profiles together.
+type extractLimits struct {
+	perFile   int64
+	remaining int64
+}
+
+// loadExtractLimits reads WORKSPACE_EXTRACT_FILE_MAX_MB (default 1024) and
+// WORKSPACE_EXTRACT_MAX_MB (default 4096).
+func loadExtractLimits() *extractLimits {
+	return &extractLimits{
+		perFile:   int64(envInt("WORKSPACE_EXTRACT_FILE_MAX_MB", 1024)) << 20,
+		remaining: int64(envInt("WORKSPACE_EXTRACT_MAX_MB", 4096)) << 20,
+	}
+}
+
+// restoreProfile unpacks profiles/<source>/ into the target profile
+// directory. Every entry is checked before anything is written, so an unsafe
+// or oversized archive leaves the existing profile alone.
+func restoreProfile(zr *zip.Reader, restore profileRestore, limits *extractLimits) error {
+	dir, err := resolveProfileDir(restore.target)
+	if err != nil {
+		return err
+	}
+
+	type entry struct {
+		file *zip.File
+		dest string
+	}
+	var entries []entry
+	var declared uint64
+	prefix := workspaceProfilesDir + restore.source + "/"
+	for _, f := range zr.File {
+		rel, ok := strings.CutPrefix(f.Name, prefix)
+		if !ok || rel == "" || strings.HasSuffix(f.Name, "/") {
+			continue
+		}
+		dest, err := profileEntryPath(dir, rel)
+		if err != nil {
+			return fmt.Errorf("%w: %v: %q", ErrInvalidArchive, err, f.Name)
+		}
+		if f.UncompressedSize64 > uint64(limits.perFile) {
+			return fmt.Errorf("%w: %q is larger than %d bytes", ErrInvalidArchive, f.Name, limits.perFile)
+		}
+		declared += f.UncompressedSize64
+		entries = append(entries, entry{file: f, dest: dest})
+	}
+	if declared > uint64(limits.remaining) {
+		return fmt.Errorf("%w: profile %s needs %d bytes, only %d left for this import", ErrInvalidArchive, restore.source, declared, limits.remaining)
+	}
+
+	if restore.replace {
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+	}
+	for _, e := range entries {
+		if err := extractZipFile(e.file, e.dest, limits); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// profileEntryPath maps a slash-separated archive path to a file below dir.
+// Backslashes and drive letters are refused outright since Windows would
+// treat them as separators or roots.
+func profileEntryPath(dir, rel string) (string, error) {
+	if strings.ContainsAny(rel, `\:`) {
+		return "", errors.New("unsafe path")
+	}
+	clean := path.Clean(rel)
+	if !filepath.IsLocal(filepath.FromSlash(clean)) {
+		return "", errors.New("unsafe path")
+	}
+	dest := filepath.Join(dir, filepath.FromSlash(clean))
+	if within, err := filepath.Rel(dir, dest); err != nil || !filepath.IsLocal(within) {
+		return "", errors.New("path leaves the profile directory")
+	}
+	return dest, nil
+}
+
+// extractZipFile writes one entry, stopping at the size limits even when the
+// archive understated the entry's size.
+func extractZipFile(f *zip.File, dest string, limits *extractLimits) error {
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return err
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	limit := min(limits.perFile, limits.remaining)
+	n, err := io.Copy(out, io.LimitReader(rc, limit+1))
+	limits.remaining -= n
+	if err == nil && n > limit {
+		err = fmt.Errorf("%w: %q exceeds the extraction size limit", ErrInvalidArchive, f.Name)
+	}
+	if err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func profileDirExists(profileName string) bool {
+	if profileName == "" {
+		return false
+	}
+	dir, err := resolveProfileDir(profileName)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(dir)
+	return err == nil
+}
+
+// validProfileName accepts the names generateProfileName produces, so an
+// archive cannot point a profile directory elsewhere.
+func validProfileName(name string) bool {
+	if name == "" || len(name) > 200 {
+		return false
+	}
+	for _, r := range name {
+		if !((r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// WorkspaceImportMaxBytes is the largest archive POST /import accepts,
+// configured in megabytes with WORKSPACE_IMPORT_MAX_MB (default 1024).
+func WorkspaceImportMaxBytes() int64 {
+	return int64(envInt("WORKSPACE_IMPORT_MAX_MB", 1024)) << 20
+}